@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"notidock/config"
+	"notidock/notification"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const usage = `Usage: notidock [command]
+
+Without a command, notidock watches Docker events and sends notifications.
+
+Commands:
+  config check                         Validate the configuration and print it with secrets redacted
+  health                               Check the health endpoint of a running notidock
+  deadletter list [-notifier name]     List notifications that could not be delivered
+  deadletter replay [-notifier name]   Re-deliver dead letters through the configured notifiers
+  deadletter purge [-notifier name]    Delete dead letters
+`
+
+// runCommand runs a CLI subcommand and returns the process exit code
+func runCommand(args []string) int {
+	switch args[0] {
+	case "config":
+		return runConfigCommand(args[1:], os.Stdout, os.Stderr)
+	case "deadletter":
+		return runDeadLetterCommand(args[1:], os.Stdout, os.Stderr)
+	case "health":
+		return runHealthCommand(os.Stdout, os.Stderr)
+	case "help", "-h", "--help":
+		fmt.Fprint(os.Stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+}
+
+func runDeadLetterCommand(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	flags := flag.NewFlagSet("deadletter "+args[0], flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	notifier := flags.String("notifier", "", "only act on dead letters of this notifier")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(stderr, "invalid configuration:", err)
+		return 1
+	}
+	spool, err := setupSpool(cfg)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	switch args[0] {
+	case "list":
+		letters, err := spool.List()
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		printDeadLetters(stdout, letters, *notifier)
+	case "replay":
+		ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer cancel()
+
+		notificationManager := setupNotificationManager(cfg, setupEnvNotifiers())
+		defer notificationManager.Close(context.Background())
+		if err := setupMessages(notificationManager, cfg); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+
+		delivered, failed, err := notificationManager.ReplayDeadLetters(ctx, spool, *notifier, true)
+		fmt.Fprintf(stdout, "delivered: %d, failed: %d\n", delivered, failed)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		if failed > 0 {
+			return 1
+		}
+	case "purge":
+		purged, err := spool.Purge(*notifier)
+		fmt.Fprintf(stdout, "purged: %d\n", purged)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	default:
+		fmt.Fprintf(stderr, "unknown deadletter command %q\n\n%s", args[0], usage)
+		return 2
+	}
+	return 0
+}
+
+// runConfigCommand validates the configuration strictly, including the
+// notifiers and routes, and prints the effective configuration
+func runConfigCommand(args []string, stdout, stderr io.Writer) int {
+	if len(args) != 1 || args[0] != "check" {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	cfg, err := config.LoadStrict()
+	if err != nil {
+		fmt.Fprintln(stderr, "invalid configuration:", err)
+		return 1
+	}
+	envNotifiers, err := checkNotifiers(cfg)
+	if err != nil {
+		fmt.Fprintln(stderr, "invalid configuration:", err)
+		return 1
+	}
+	if _, err := notification.NewTemplates(cfg.Templates); err != nil {
+		fmt.Fprintln(stderr, "invalid configuration:", err)
+		return 1
+	}
+
+	if cfg.ConfigFile != "" {
+		fmt.Fprintf(stdout, "# configuration file: %s\n", cfg.ConfigFile)
+	}
+	if len(envNotifiers) > 0 {
+		fmt.Fprintf(stdout, "# notifiers from environment variables: %s\n", strings.Join(envNotifiers, ", "))
+	}
+	encoder := yaml.NewEncoder(stdout)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(cfg.Redacted()); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// checkNotifiers creates every configured notifier and checks the routes
+// against them, returning the names of the environment notifiers
+func checkNotifiers(cfg config.AppConfig) ([]string, error) {
+	var errs []error
+	var names, envNames []string
+	for _, nc := range cfg.Notifiers {
+		if _, err := buildNotifier(nc); err != nil {
+			errs = append(errs, fmt.Errorf("notifiers[%s]: %w", nc.Name, err))
+		}
+		names = append(names, nc.Name)
+	}
+
+	envNotifiers, err := loadEnvNotifiers()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for _, n := range envNotifiers {
+		envNames = append(envNames, n.Name())
+		if !slices.Contains(names, n.Name()) {
+			names = append(names, n.Name())
+		}
+	}
+
+	if err := notification.ValidateRoutes(buildRoutes(cfg.Routes), names); err != nil {
+		errs = append(errs, err)
+	}
+	return envNames, errors.Join(errs...)
+}
+
+// runHealthCommand queries the status server of a running instance. It is
+// used as the container HEALTHCHECK, so without a status server there is
+// nothing to check and it succeeds.
+func runHealthCommand(stdout, stderr io.Writer) int {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(stderr, "invalid configuration:", err)
+		return 1
+	}
+	if cfg.StatusAddr == "" {
+		fmt.Fprintln(stdout, "status server disabled")
+		return 0
+	}
+
+	host, port, err := net.SplitHostPort(cfg.StatusAddr)
+	if err != nil {
+		fmt.Fprintln(stderr, "invalid status address:", err)
+		return 1
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+
+	client := &http.Client{Timeout: 3 * time.Second}
+	resp, err := client.Get("http://" + net.JoinHostPort(host, port) + "/health")
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	defer resp.Body.Close()
+
+	io.Copy(stdout, resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return 1
+	}
+	return 0
+}
+
+func printDeadLetters(w io.Writer, letters []notification.DeadLetter, notifier string) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNOTIFIER\tCONTAINER\tACTION\tFAILED AT\tREDELIVERIES\tPARKED\tERROR")
+	for _, l := range letters {
+		if notifier != "" && l.Notifier != notifier {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\t%t\t%s\n",
+			l.ID, l.Notifier, l.Event.ContainerName, l.Event.Action,
+			l.FailedAt.Format(time.RFC3339), l.Redeliveries, l.Parked, l.Error,
+		)
+	}
+	tw.Flush()
+}
+
+// setupSpool opens the dead-letter spool in the state directory
+func setupSpool(cfg config.AppConfig) (*notification.Spool, error) {
+	if cfg.StateDir == "" {
+		return nil, errors.New("dead letters are disabled: " + config.EnvPrefix + config.KeyStateDir + " is not set")
+	}
+	return notification.NewSpool(filepath.Join(cfg.StateDir, "deadletter"), cfg.DeadLetterMax)
+}
@@ -1,8 +1,11 @@
 package config
 
 import (
+	"encoding/json"
+	"fmt"
 	"log/slog"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -11,6 +14,8 @@ import (
 // Constants for environment variable keys
 const (
 	EnvPrefix = "NOTIDOCK_"
+	// FileSuffix marks a variable naming a file to read the value from
+	FileSuffix = "_FILE"
 
 	KeyMonitorAll           = "MONITOR_ALL"
 	KeyTrackedEvents        = "TRACKED_EVENTS"
@@ -18,87 +23,472 @@ const (
 	KeyMonitorHealth        = "MONITOR_HEALTH"
 	KeyHealthTimeout        = "HEALTH_TIMEOUT"
 	KeyMaxFailingStreak     = "MAX_FAILING_STREAK"
+	KeyHealthLogEntries     = "HEALTH_LOG_ENTRIES"
+	KeyStartupInventory     = "STARTUP_INVENTORY"
+	KeyHealthFlapWindow     = "HEALTH_FLAP_WINDOW"
+	KeyHealthFlapHigh       = "HEALTH_FLAP_HIGH"
+	KeyHealthFlapLow        = "HEALTH_FLAP_LOW"
 	KeyDockerSocket         = "DOCKER_SOCKET"
 	KeyWindowDuration       = "WINDOW_DURATION"
 	KeyEventThreshold       = "EVENT_THRESHOLD"
 	KeyNotificationCooldown = "NOTIFICATION_COOLDOWN"
+	KeyPauseUntilRecovered  = "THROTTLE_PAUSE_UNTIL_RECOVERED"
+	KeyThrottleStrategies   = "THROTTLE_STRATEGIES"
+	KeyThrottleKey          = "THROTTLE_KEY"
+	KeyThrottleBurst        = "THROTTLE_BURST"
+	KeyThrottleRefill       = "THROTTLE_REFILL_INTERVAL"
+	KeyGlobalThreshold      = "GLOBAL_EVENT_THRESHOLD"
+	KeyGlobalWindow         = "GLOBAL_WINDOW_DURATION"
+	KeyCrashLoopThreshold   = "CRASHLOOP_THRESHOLD"
+	KeyCrashLoopWindow      = "CRASHLOOP_WINDOW"
+	KeyCrashLoopStable      = "CRASHLOOP_STABLE"
+	KeyCorrelationWindow    = "CORRELATION_WINDOW"
+	KeyQueueSize            = "QUEUE_SIZE"
+	KeyQueueWorkers         = "QUEUE_WORKERS"
+	KeyQueueOverflow        = "QUEUE_OVERFLOW"
+	KeyStatusAddr           = "STATUS_ADDR"
+	KeyRetryMaxAttempts     = "RETRY_MAX_ATTEMPTS"
+	KeyRetryInitialBackoff  = "RETRY_INITIAL_BACKOFF"
+	KeyRetryMaxBackoff      = "RETRY_MAX_BACKOFF"
+	KeyRetryTimeout         = "RETRY_TIMEOUT"
+	KeyStateDir             = "STATE_DIR"
+	KeyDeadLetterInterval   = "DEADLETTER_INTERVAL"
+	KeyDeadLetterMax        = "DEADLETTER_MAX"
+	KeyReconcile            = "RECONCILE"
+	KeyCircuitThreshold     = "CIRCUIT_FAILURE_THRESHOLD"
+	KeyCircuitCooldown      = "CIRCUIT_COOLDOWN"
+	KeyRoutes               = "ROUTES"
+	KeyConfigFile           = "CONFIG_FILE"
+	KeyConfigWatchInterval  = "CONFIG_WATCH_INTERVAL"
+	KeyStrictConfig         = "STRICT_CONFIG"
+	KeyTemplates            = "TEMPLATES"
+	KeyTimeZone             = "TIME_ZONE"
+	KeyTimeFormat           = "TIME_FORMAT"
+	KeyRelativeTime         = "RELATIVE_TIME"
 )
 
 // Default values
 const (
 	DefaultHealthTimeout        = 60 * time.Second
 	DefaultMaxFailingStreak     = 3
+	DefaultHealthLogEntries     = 3
+	DefaultStartupInventory     = false
+	DefaultHealthFlapWindow     = 10 * time.Minute
+	DefaultHealthFlapHigh       = 6
+	DefaultHealthFlapLow        = 2
 	DefaultMonitorAll           = false
 	DefaultMonitorHealth        = false
 	DefaultDockerSocket         = "unix:///var/run/docker.sock"
 	DefaultWindowDuration       = 60 * time.Second
 	DefaultEventThreshold       = 20
 	DefaultNotificationCooldown = 0 * time.Second
+	DefaultPauseUntilRecovered  = false
+	DefaultThrottleStrategies   = ThrottleSlidingWindow
+	DefaultThrottleKey          = ThrottleKeyContainer + "," + ThrottleKeyImage
+	DefaultThrottleBurst        = 10
+	DefaultThrottleRefill       = 30 * time.Second
+	DefaultGlobalThreshold      = 30
+	DefaultGlobalWindow         = time.Minute
+	DefaultCrashLoopThreshold   = 5
+	DefaultCrashLoopWindow      = 5 * time.Minute
+	DefaultCrashLoopStable      = 2 * time.Minute
+	DefaultCorrelationWindow    = 5 * time.Second
 	DefaultTrackedEvents        = "create,start,die,stop,kill"
+	DefaultQueueSize            = 100
+	DefaultQueueWorkers         = 1
+	DefaultQueueOverflow        = "drop_oldest"
+	DefaultStatusAddr           = ""
+	DefaultRetryMaxAttempts     = 3
+	DefaultRetryInitialBackoff  = 1 * time.Second
+	DefaultRetryMaxBackoff      = 30 * time.Second
+	DefaultRetryTimeout         = 2 * time.Minute
+	DefaultStateDir             = ""
+	DefaultDeadLetterInterval   = 5 * time.Minute
+	DefaultDeadLetterMax        = 1000
+	DefaultReconcile            = true
+	DefaultCircuitThreshold     = 5
+	DefaultCircuitCooldown      = 1 * time.Minute
+	DefaultConfigWatchInterval  = 0 * time.Second
+	DefaultStrictConfig         = false
+	DefaultTimeZone             = ""
+	DefaultTimeFormat           = "RFC3339"
+	DefaultRelativeTime         = false
 )
 
+// Queue overflow policies
+var queueOverflowPolicies = []string{"drop_oldest", "drop_newest", "block"}
+
+// Throttling strategies, applied together when several are selected
+const (
+	// ThrottleSlidingWindow limits the events of each container and image
+	// within WindowDuration
+	ThrottleSlidingWindow = "sliding_window"
+	// ThrottleTokenBucket lets each container and image send a burst, then
+	// one event per refill interval
+	ThrottleTokenBucket = "token_bucket"
+	// ThrottleGlobal limits the events of all containers together
+	ThrottleGlobal = "global"
+)
+
+var throttleStrategies = []string{ThrottleSlidingWindow, ThrottleTokenBucket, ThrottleGlobal}
+
+// Parts of the key notifications are throttled by. Events with the same
+// values for the selected parts share their limits.
+const (
+	ThrottleKeyContainer = "container"
+	ThrottleKeyImage     = "image"
+	// ThrottleKeyService is the compose service, shared by its replicas
+	ThrottleKeyService = "compose_service"
+	// ThrottleKeyProject is the compose project
+	ThrottleKeyProject = "compose_project"
+	ThrottleKeyAction  = "action"
+)
+
+var throttleKeyParts = []string{ThrottleKeyContainer, ThrottleKeyImage, ThrottleKeyService, ThrottleKeyProject, ThrottleKeyAction}
+
+// RouteConfig is a notification routing rule. Routes are given in the
+// configuration file or as a JSON array in NOTIDOCK_ROUTES.
+type RouteConfig struct {
+	Name            string            `json:"name" yaml:"name"`
+	Containers      []string          `json:"containers" yaml:"containers"`
+	Images          []string          `json:"images" yaml:"images"`
+	ComposeProjects []string          `json:"compose_projects" yaml:"compose_projects"`
+	Labels          map[string]string `json:"labels" yaml:"labels"`
+	Actions         []string          `json:"actions" yaml:"actions"`
+	ExitCodes       []string          `json:"exit_codes" yaml:"exit_codes"`
+	Severities      []string          `json:"severities" yaml:"severities"`
+	Notifiers       []string          `json:"notifiers" yaml:"notifiers"`
+	Continue        bool              `json:"continue" yaml:"continue"`
+}
+
 // AppConfig holds all application configuration
 type AppConfig struct {
 	// Container monitoring
-	MonitorAllContainers bool
-	TrackedEvents        []string
-	TrackedExitCodes     []string
+	MonitorAllContainers bool     `yaml:"monitor_all"`
+	TrackedEvents        []string `yaml:"tracked_events"`
+	TrackedExitCodes     []string `yaml:"tracked_exitcodes"`
 
 	// Health checking
-	MonitorHealth      bool
-	HealthCheckTimeout time.Duration
-	MaxFailingStreak   int
+	MonitorHealth bool `yaml:"monitor_health"`
+	// Deprecated: health is no longer polled, these are ignored
+	HealthCheckTimeout time.Duration `yaml:"health_timeout"`
+	MaxFailingStreak   int           `yaml:"max_failing_streak"`
+	// HealthLogEntries is how many health check results are included in
+	// unhealthy notifications
+	HealthLogEntries int `yaml:"health_log_entries"`
+	// StartupInventory sends a summary of the unhealthy and exited
+	// containers when notidock starts
+	StartupInventory bool `yaml:"startup_inventory"`
+	// A container flaps when its health changed HealthFlapHigh times within
+	// HealthFlapWindow, until no more than HealthFlapLow changes are left
+	HealthFlapWindow time.Duration `yaml:"health_flap_window"`
+	HealthFlapHigh   int           `yaml:"health_flap_high"`
+	HealthFlapLow    int           `yaml:"health_flap_low"`
 
 	// Docker connection
-	DockerSocket string
+	DockerSocket string `yaml:"docker_socket"`
 
 	// Throttling
-	WindowDuration       time.Duration
-	EventThreshold       int
-	NotificationCooldown time.Duration
+	WindowDuration       time.Duration `yaml:"window_duration"`
+	EventThreshold       int           `yaml:"event_threshold"`
+	NotificationCooldown time.Duration `yaml:"notification_cooldown"`
+	// PauseUntilRecovered keeps throttled notifications paused until the
+	// exceeded limit recovers when no cooldown is set, instead of resuming
+	// them with the next event
+	PauseUntilRecovered bool `yaml:"throttle_pause_until_recovered"`
+	// ThrottleStrategies selects the limits applied to notifications
+	ThrottleStrategies []string `yaml:"throttle_strategies"`
+	// ThrottleKey selects what notifications are throttled by
+	ThrottleKey            []string      `yaml:"throttle_key"`
+	ThrottleBurst          int           `yaml:"throttle_burst"`
+	ThrottleRefillInterval time.Duration `yaml:"throttle_refill_interval"`
+	GlobalEventThreshold   int           `yaml:"global_event_threshold"`
+	GlobalWindowDuration   time.Duration `yaml:"global_window_duration"`
+
+	// Crash loop detection
+	CrashLoopThreshold int           `yaml:"crashloop_threshold"`
+	CrashLoopWindow    time.Duration `yaml:"crashloop_window"`
+	CrashLoopStable    time.Duration `yaml:"crashloop_stable"`
+
+	// CorrelationWindow is how long the kill, die, stop, destroy and oom
+	// events of a container are held to be merged into one notification
+	CorrelationWindow time.Duration `yaml:"correlation_window"`
+
+	// Notification delivery
+	QueueSize     int    `yaml:"queue_size"`
+	QueueWorkers  int    `yaml:"queue_workers"`
+	QueueOverflow string `yaml:"queue_overflow"`
+
+	// Routing
+	Routes []RouteConfig `yaml:"routes"`
+
+	// Message templates by name, such as "title" or "die.title"
+	Templates map[string]string `yaml:"templates"`
+
+	// Timestamps in notifications
+	TimeZone     string `yaml:"time_zone"`
+	TimeFormat   string `yaml:"time_format"`
+	RelativeTime bool   `yaml:"relative_time"`
+
+	// Circuit breaker
+	CircuitFailureThreshold int           `yaml:"circuit_failure_threshold"`
+	CircuitCooldown         time.Duration `yaml:"circuit_cooldown"`
+
+	// Retries
+	RetryMaxAttempts    int           `yaml:"retry_max_attempts"`
+	RetryInitialBackoff time.Duration `yaml:"retry_initial_backoff"`
+	RetryMaxBackoff     time.Duration `yaml:"retry_max_backoff"`
+	RetryTimeout        time.Duration `yaml:"retry_timeout"`
+
+	// State and dead letters
+	StateDir           string        `yaml:"state_dir"`
+	DeadLetterInterval time.Duration `yaml:"deadletter_interval"`
+	DeadLetterMax      int           `yaml:"deadletter_max"`
+	// Reconcile notifies the container changes missed while notidock was
+	// not running, detected from the state kept in StateDir
+	Reconcile bool `yaml:"reconcile"`
+
+	// Status server
+	StatusAddr string `yaml:"status_addr"`
+
+	// Notifiers defined in the configuration file
+	Notifiers []NotifierConfig `yaml:"notifiers"`
+
+	// Configuration reloading
+	ConfigWatchInterval time.Duration `yaml:"config_watch_interval"`
+
+	// StrictConfig makes invalid settings stop notidock instead of
+	// falling back to their defaults
+	StrictConfig bool `yaml:"strict_config"`
+
+	// ConfigFile is the file the configuration was loaded from, if any
+	ConfigFile string `yaml:"-"`
 }
 
-// GetConfig returns the complete application configuration
+// GetConfig returns the application configuration from environment
+// variables alone. Use Load to include the configuration file.
 func GetConfig() AppConfig {
+	cfg, errs := applyEnv(defaultConfig())
+	warnInvalid(errs)
+	return cfg
+}
+
+// defaultConfig returns the configuration used when nothing is set
+func defaultConfig() AppConfig {
+	return AppConfig{
+		MonitorAllContainers:    DefaultMonitorAll,
+		TrackedEvents:           strings.Split(DefaultTrackedEvents, ","),
+		MonitorHealth:           DefaultMonitorHealth,
+		HealthCheckTimeout:      DefaultHealthTimeout,
+		MaxFailingStreak:        DefaultMaxFailingStreak,
+		HealthLogEntries:        DefaultHealthLogEntries,
+		StartupInventory:        DefaultStartupInventory,
+		HealthFlapWindow:        DefaultHealthFlapWindow,
+		HealthFlapHigh:          DefaultHealthFlapHigh,
+		HealthFlapLow:           DefaultHealthFlapLow,
+		DockerSocket:            DefaultDockerSocket,
+		WindowDuration:          DefaultWindowDuration,
+		EventThreshold:          DefaultEventThreshold,
+		NotificationCooldown:    DefaultNotificationCooldown,
+		PauseUntilRecovered:     DefaultPauseUntilRecovered,
+		ThrottleStrategies:      strings.Split(DefaultThrottleStrategies, ","),
+		ThrottleKey:             strings.Split(DefaultThrottleKey, ","),
+		ThrottleBurst:           DefaultThrottleBurst,
+		ThrottleRefillInterval:  DefaultThrottleRefill,
+		GlobalEventThreshold:    DefaultGlobalThreshold,
+		GlobalWindowDuration:    DefaultGlobalWindow,
+		CrashLoopThreshold:      DefaultCrashLoopThreshold,
+		CrashLoopWindow:         DefaultCrashLoopWindow,
+		CrashLoopStable:         DefaultCrashLoopStable,
+		CorrelationWindow:       DefaultCorrelationWindow,
+		QueueSize:               DefaultQueueSize,
+		QueueWorkers:            DefaultQueueWorkers,
+		QueueOverflow:           DefaultQueueOverflow,
+		CircuitFailureThreshold: DefaultCircuitThreshold,
+		CircuitCooldown:         DefaultCircuitCooldown,
+		RetryMaxAttempts:        DefaultRetryMaxAttempts,
+		RetryInitialBackoff:     DefaultRetryInitialBackoff,
+		RetryMaxBackoff:         DefaultRetryMaxBackoff,
+		RetryTimeout:            DefaultRetryTimeout,
+		StateDir:                DefaultStateDir,
+		DeadLetterInterval:      DefaultDeadLetterInterval,
+		DeadLetterMax:           DefaultDeadLetterMax,
+		Reconcile:               DefaultReconcile,
+		StatusAddr:              DefaultStatusAddr,
+		TimeZone:                DefaultTimeZone,
+		TimeFormat:              DefaultTimeFormat,
+		RelativeTime:            DefaultRelativeTime,
+		ConfigWatchInterval:     DefaultConfigWatchInterval,
+		StrictConfig:            DefaultStrictConfig,
+	}
+}
+
+// applyEnv overrides cfg with the environment variables that are set.
+// Variables that cannot be parsed keep the value from cfg and are returned
+// as errors.
+func applyEnv(cfg AppConfig) (AppConfig, []error) {
+	r := &envReader{}
 	return AppConfig{
 		// Container monitoring
-		MonitorAllContainers: EnvOrDefault(KeyMonitorAll, DefaultMonitorAll, parseBool),
-		TrackedEvents:        EnvOrDefault(KeyTrackedEvents, strings.Split(DefaultTrackedEvents, ","), parseStringSlice),
-		TrackedExitCodes:     EnvOrDefault(KeyTrackedExitCodes, []string(nil), parseStringSlice),
+		MonitorAllContainers: readEnv(r, KeyMonitorAll, cfg.MonitorAllContainers, parseBool),
+		TrackedEvents:        readEnv(r, KeyTrackedEvents, cfg.TrackedEvents, parseStringSlice),
+		TrackedExitCodes:     readEnv(r, KeyTrackedExitCodes, cfg.TrackedExitCodes, parseStringSlice),
 
 		// Health checking
-		MonitorHealth:      EnvOrDefault(KeyMonitorHealth, DefaultMonitorHealth, parseBool),
-		HealthCheckTimeout: EnvOrDefault(KeyHealthTimeout, DefaultHealthTimeout, parseDuration),
-		MaxFailingStreak:   EnvOrDefault(KeyMaxFailingStreak, DefaultMaxFailingStreak, parseInt),
+		MonitorHealth:      readEnv(r, KeyMonitorHealth, cfg.MonitorHealth, parseBool),
+		HealthCheckTimeout: readEnv(r, KeyHealthTimeout, cfg.HealthCheckTimeout, parseDuration),
+		MaxFailingStreak:   readEnv(r, KeyMaxFailingStreak, cfg.MaxFailingStreak, parseInt),
+		HealthLogEntries:   readEnv(r, KeyHealthLogEntries, cfg.HealthLogEntries, parseNonNegativeInt),
+		StartupInventory:   readEnv(r, KeyStartupInventory, cfg.StartupInventory, parseBool),
+		HealthFlapWindow:   readEnv(r, KeyHealthFlapWindow, cfg.HealthFlapWindow, parsePositiveDuration),
+		HealthFlapHigh:     readEnv(r, KeyHealthFlapHigh, cfg.HealthFlapHigh, parseNonNegativeInt),
+		HealthFlapLow:      readEnv(r, KeyHealthFlapLow, cfg.HealthFlapLow, parseNonNegativeInt),
 
 		// Docker connection
-		DockerSocket: EnvOrDefault(KeyDockerSocket, DefaultDockerSocket, parseString),
+		DockerSocket: readEnv(r, KeyDockerSocket, cfg.DockerSocket, parseString),
 
 		// Throttling
-		WindowDuration:       EnvOrDefault(KeyWindowDuration, DefaultWindowDuration, parseDuration),
-		EventThreshold:       EnvOrDefault(KeyEventThreshold, DefaultEventThreshold, parseInt),
-		NotificationCooldown: EnvOrDefault(KeyNotificationCooldown, DefaultNotificationCooldown, parseDuration),
-	}
+		WindowDuration:         readEnv(r, KeyWindowDuration, cfg.WindowDuration, parseDuration),
+		EventThreshold:         readEnv(r, KeyEventThreshold, cfg.EventThreshold, parseInt),
+		NotificationCooldown:   readEnv(r, KeyNotificationCooldown, cfg.NotificationCooldown, parseDuration),
+		PauseUntilRecovered:    readEnv(r, KeyPauseUntilRecovered, cfg.PauseUntilRecovered, parseBool),
+		ThrottleStrategies:     readEnv(r, KeyThrottleStrategies, cfg.ThrottleStrategies, parseThrottleStrategies),
+		ThrottleKey:            readEnv(r, KeyThrottleKey, cfg.ThrottleKey, parseThrottleKey),
+		ThrottleBurst:          readEnv(r, KeyThrottleBurst, cfg.ThrottleBurst, parsePositiveInt),
+		ThrottleRefillInterval: readEnv(r, KeyThrottleRefill, cfg.ThrottleRefillInterval, parsePositiveDuration),
+		GlobalEventThreshold:   readEnv(r, KeyGlobalThreshold, cfg.GlobalEventThreshold, parseNonNegativeInt),
+		GlobalWindowDuration:   readEnv(r, KeyGlobalWindow, cfg.GlobalWindowDuration, parsePositiveDuration),
+
+		// Crash loop detection
+		CrashLoopThreshold: readEnv(r, KeyCrashLoopThreshold, cfg.CrashLoopThreshold, parseNonNegativeInt),
+		CrashLoopWindow:    readEnv(r, KeyCrashLoopWindow, cfg.CrashLoopWindow, parsePositiveDuration),
+		CrashLoopStable:    readEnv(r, KeyCrashLoopStable, cfg.CrashLoopStable, parsePositiveDuration),
+
+		// Event correlation
+		CorrelationWindow: readEnv(r, KeyCorrelationWindow, cfg.CorrelationWindow, parseDuration),
+
+		// Notification delivery
+		QueueSize:     readEnv(r, KeyQueueSize, cfg.QueueSize, parsePositiveInt),
+		QueueWorkers:  readEnv(r, KeyQueueWorkers, cfg.QueueWorkers, parsePositiveInt),
+		QueueOverflow: readEnv(r, KeyQueueOverflow, cfg.QueueOverflow, parseOneOf(queueOverflowPolicies)),
+
+		// Routing
+		Routes: readEnv(r, KeyRoutes, cfg.Routes, parseRoutes),
+
+		// Message templates
+		Templates: readEnv(r, KeyTemplates, cfg.Templates, parseTemplates),
+
+		// Timestamps in notifications
+		TimeZone:     readEnv(r, KeyTimeZone, cfg.TimeZone, parseTimeZone),
+		TimeFormat:   readEnv(r, KeyTimeFormat, cfg.TimeFormat, parseString),
+		RelativeTime: readEnv(r, KeyRelativeTime, cfg.RelativeTime, parseBool),
+
+		// Circuit breaker
+		CircuitFailureThreshold: readEnv(r, KeyCircuitThreshold, cfg.CircuitFailureThreshold, parseNonNegativeInt),
+		CircuitCooldown:         readEnv(r, KeyCircuitCooldown, cfg.CircuitCooldown, parsePositiveDuration),
+
+		// Retries
+		RetryMaxAttempts:    readEnv(r, KeyRetryMaxAttempts, cfg.RetryMaxAttempts, parsePositiveInt),
+		RetryInitialBackoff: readEnv(r, KeyRetryInitialBackoff, cfg.RetryInitialBackoff, parseDuration),
+		RetryMaxBackoff:     readEnv(r, KeyRetryMaxBackoff, cfg.RetryMaxBackoff, parseDuration),
+		RetryTimeout:        readEnv(r, KeyRetryTimeout, cfg.RetryTimeout, parseDuration),
+
+		// State and dead letters
+		StateDir:           readEnv(r, KeyStateDir, cfg.StateDir, parseString),
+		DeadLetterInterval: readEnv(r, KeyDeadLetterInterval, cfg.DeadLetterInterval, parsePositiveDuration),
+		DeadLetterMax:      readEnv(r, KeyDeadLetterMax, cfg.DeadLetterMax, parsePositiveInt),
+		Reconcile:          readEnv(r, KeyReconcile, cfg.Reconcile, parseBool),
+
+		// Status server
+		StatusAddr: readEnv(r, KeyStatusAddr, cfg.StatusAddr, parseString),
+
+		// Configuration reloading
+		ConfigWatchInterval: readEnv(r, KeyConfigWatchInterval, cfg.ConfigWatchInterval, parseDuration),
+
+		// Validation
+		StrictConfig: readEnv(r, KeyStrictConfig, cfg.StrictConfig, parseBool),
+
+		// Only set in the configuration file
+		Notifiers:  cfg.Notifiers,
+		ConfigFile: cfg.ConfigFile,
+	}, r.errs
 }
 
 func EnvOrDefault[T any](key string, defaultValue T, parser func(string) (T, error)) T {
-	if value, exists := os.LookupEnv(EnvPrefix + key); exists && value != "" {
-		if parsed, err := parser(value); err == nil {
-			return parsed
-		}
+	value, err := parseEnv(key, defaultValue, parser)
+	if err != nil {
 		// Log warning about invalid value and fallback to default
 		slog.Warn("invalid environment variable value",
-			"key", EnvPrefix+key,
-			"value", value,
+			"error", err,
 			"fallback", defaultValue,
 		)
 	}
-	return defaultValue
+	return value
+}
+
+// LookupEnv returns the value of the environment variable name or, when
+// name_FILE is set instead, the contents of that file without surrounding
+// whitespace. This lets secrets such as webhook URLs be mounted as Docker
+// secrets under /run/secrets rather than shown by docker inspect.
+func LookupEnv(name string) (string, error) {
+	value := os.Getenv(name)
+	path := os.Getenv(name + FileSuffix)
+	switch {
+	case path == "":
+		return value, nil
+	case value != "":
+		return "", fmt.Errorf("only one of %s and %s%s may be set", name, name, FileSuffix)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("%s%s: %w", name, FileSuffix, err)
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+// parseEnv parses the environment variable, returning current when it is
+// not set or cannot be parsed. Values read from files are left out of the
+// errors, as they are usually secrets.
+func parseEnv[T any](key string, current T, parser func(string) (T, error)) (T, error) {
+	name := EnvPrefix + key
+	value, err := LookupEnv(name)
+	if err != nil || value == "" {
+		return current, err
+	}
+	parsed, err := parser(value)
+	if err != nil {
+		if os.Getenv(name+FileSuffix) != "" {
+			return current, fmt.Errorf("%s%s: invalid value", name, FileSuffix)
+		}
+		return current, fmt.Errorf("%s: invalid value %q: %w", name, value, err)
+	}
+	return parsed, nil
+}
+
+// envReader collects the environment variables that could not be parsed
+type envReader struct {
+	errs []error
+}
+
+func readEnv[T any](r *envReader, key string, current T, parser func(string) (T, error)) T {
+	value, err := parseEnv(key, current, parser)
+	if err != nil {
+		r.errs = append(r.errs, err)
+	}
+	return value
+}
+
+// warnInvalid logs settings that were ignored because they are invalid
+func warnInvalid(errs []error) {
+	for _, err := range errs {
+		slog.Warn("invalid configuration value, using the default", "error", err)
+	}
 }
 
 // Parsers for different types
 func parseBool(s string) (bool, error) {
-	return s == "true", nil
+	return strconv.ParseBool(s)
 }
 
 func parseString(s string) (string, error) {
@@ -109,10 +499,106 @@ func parseInt(s string) (int, error) {
 	return strconv.Atoi(s)
 }
 
+func parsePositiveInt(s string) (int, error) {
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	return i, positive(i)
+}
+
+func parseNonNegativeInt(s string) (int, error) {
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	return i, nonNegative(i)
+}
+
+func parseOneOf(allowed []string) func(string) (string, error) {
+	return func(s string) (string, error) {
+		return s, oneOf(allowed, s)
+	}
+}
+
+func parseTimeZone(s string) (string, error) {
+	return s, timeZone(s)
+}
+
 func parseDuration(s string) (time.Duration, error) {
 	return time.ParseDuration(s)
 }
 
+func parsePositiveDuration(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	return d, positiveDuration(d)
+}
+
+func positive(i int) error {
+	if i <= 0 {
+		return fmt.Errorf("must be greater than 0, got %d", i)
+	}
+	return nil
+}
+
+func nonNegative(i int) error {
+	if i < 0 {
+		return fmt.Errorf("must not be negative, got %d", i)
+	}
+	return nil
+}
+
+func timeZone(s string) error {
+	if _, err := time.LoadLocation(s); err != nil {
+		return fmt.Errorf("unknown time zone %q", s)
+	}
+	return nil
+}
+
+func nonNegativeDuration(d time.Duration) error {
+	if d < 0 {
+		return fmt.Errorf("must not be negative, got %s", d)
+	}
+	return nil
+}
+
+func positiveDuration(d time.Duration) error {
+	if d <= 0 {
+		return fmt.Errorf("must be greater than 0, got %s", d)
+	}
+	return nil
+}
+
+func oneOf(allowed []string, s string) error {
+	if !slices.Contains(allowed, s) {
+		return fmt.Errorf("must be one of %s, got %q", strings.Join(allowed, ", "), s)
+	}
+	return nil
+}
+
+func parseThrottleStrategies(s string) ([]string, error) {
+	strategies, err := parseStringSlice(s)
+	for _, strategy := range strategies {
+		if err := oneOf(throttleStrategies, strategy); err != nil {
+			return nil, err
+		}
+	}
+	return strategies, err
+}
+
+func parseThrottleKey(s string) ([]string, error) {
+	parts, err := parseStringSlice(s)
+	for _, part := range parts {
+		if err := oneOf(throttleKeyParts, part); err != nil {
+			return nil, err
+		}
+	}
+	return parts, err
+}
+
 func parseStringSlice(s string) ([]string, error) {
 	if s == "" {
 		return nil, nil
@@ -130,8 +616,29 @@ func parseStringSlice(s string) ([]string, error) {
 	return result, nil
 }
 
+func parseRoutes(s string) ([]RouteConfig, error) {
+	decoder := json.NewDecoder(strings.NewReader(s))
+	decoder.DisallowUnknownFields()
+	var routes []RouteConfig
+	if err := decoder.Decode(&routes); err != nil {
+		return nil, err
+	}
+	return routes, nil
+}
+
+func parseTemplates(s string) (map[string]string, error) {
+	var templates map[string]string
+	if err := json.Unmarshal([]byte(s), &templates); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
 func (c AppConfig) Log() {
-	slog.Info("notidock started with configuration")
+	slog.Info("notidock started with configuration",
+		"config_file", formatDisabled(c.ConfigFile),
+		"config_watch_interval", formatDuration(c.ConfigWatchInterval),
+	)
 
 	// Container monitoring settings
 	slog.Info("container monitoring settings",
@@ -143,13 +650,16 @@ func (c AppConfig) Log() {
 	// Health check settings
 	slog.Info("health check settings",
 		"enabled", c.MonitorHealth,
-		"timeout", c.HealthCheckTimeout,
-		"max_failing_streak", c.MaxFailingStreak,
+		"log_entries", c.HealthLogEntries,
+		"startup_inventory", c.StartupInventory,
+		"flap_window", c.HealthFlapWindow,
+		"flap_high", formatThreshold(c.HealthFlapHigh),
+		"flap_low", c.HealthFlapLow,
 	)
 
 	// Docker connection settings
 	slog.Info("docker connection settings",
-		"socket_path", c.DockerSocket,
+		"socket_path", redactPassword(c.DockerSocket),
 	)
 
 	// Throttling settings
@@ -157,6 +667,73 @@ func (c AppConfig) Log() {
 		"window_duration", c.WindowDuration,
 		"event_threshold", c.EventThreshold,
 		"notification_cooldown", formatDuration(c.NotificationCooldown),
+		"pause_until_recovered", c.PauseUntilRecovered,
+		"strategies", c.ThrottleStrategies,
+		"key", c.ThrottleKey,
+		"burst", c.ThrottleBurst,
+		"refill_interval", c.ThrottleRefillInterval,
+		"global_event_threshold", formatThreshold(c.GlobalEventThreshold),
+		"global_window_duration", c.GlobalWindowDuration,
+	)
+
+	// Crash loop detection settings
+	slog.Info("crash loop settings",
+		"threshold", formatThreshold(c.CrashLoopThreshold),
+		"window", c.CrashLoopWindow,
+		"stable", c.CrashLoopStable,
+	)
+
+	// Event correlation settings
+	slog.Info("event correlation settings",
+		"window", formatDuration(c.CorrelationWindow),
+	)
+
+	// Notification delivery settings
+	slog.Info("notification delivery settings",
+		"queue_size", c.QueueSize,
+		"queue_workers", c.QueueWorkers,
+		"queue_overflow", c.QueueOverflow,
+	)
+
+	// Routing settings
+	slog.Info("routing settings",
+		"routes", formatRoutes(c.Routes),
+		"file_notifiers", len(c.Notifiers),
+		"templates", formatTemplates(c.Templates),
+	)
+
+	// Timestamp settings
+	slog.Info("timestamp settings",
+		"time_zone", formatTimeZone(c.TimeZone),
+		"time_format", c.TimeFormat,
+		"relative_time", c.RelativeTime,
+	)
+
+	// Circuit breaker settings
+	slog.Info("circuit breaker settings",
+		"failure_threshold", formatThreshold(c.CircuitFailureThreshold),
+		"cooldown", c.CircuitCooldown,
+	)
+
+	// Retry settings
+	slog.Info("retry settings",
+		"max_attempts", c.RetryMaxAttempts,
+		"initial_backoff", c.RetryInitialBackoff,
+		"max_backoff", c.RetryMaxBackoff,
+		"timeout", formatDuration(c.RetryTimeout),
+	)
+
+	// State and dead-letter settings
+	slog.Info("state settings",
+		"state_dir", formatDisabled(c.StateDir),
+		"deadletter_interval", c.DeadLetterInterval,
+		"deadletter_max", c.DeadLetterMax,
+		"reconcile", c.Reconcile && c.StateDir != "",
+	)
+
+	// Status server settings
+	slog.Info("status server settings",
+		"addr", formatDisabled(c.StatusAddr),
 	)
 }
 
@@ -167,6 +744,41 @@ func formatExitCodes(codes []string) any {
 	return codes
 }
 
+func formatRoutes(routes []RouteConfig) any {
+	if len(routes) == 0 {
+		return "all notifiers"
+	}
+	return len(routes)
+}
+
+func formatTimeZone(s string) string {
+	if s == "" {
+		return "local"
+	}
+	return s
+}
+
+func formatTemplates(templates map[string]string) any {
+	if len(templates) == 0 {
+		return "default"
+	}
+	return len(templates)
+}
+
+func formatThreshold(n int) any {
+	if n == 0 {
+		return "disabled"
+	}
+	return n
+}
+
+func formatDisabled(s string) string {
+	if s == "" {
+		return "disabled"
+	}
+	return s
+}
+
 func formatDuration(d time.Duration) string {
 	if d == 0 {
 		return "disabled"
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -38,30 +39,102 @@ func TestGetConfig(t *testing.T) {
 				"NOTIDOCK_MONITOR_HEALTH":     "true",
 				"NOTIDOCK_HEALTH_TIMEOUT":     "120s",
 				"NOTIDOCK_MAX_FAILING_STREAK": "5",
+				"NOTIDOCK_HEALTH_LOG_ENTRIES": "0",
+				"NOTIDOCK_STARTUP_INVENTORY":  "true",
 			},
 			expected: func() AppConfig {
 				cfg := getDefaultConfig()
 				cfg.MonitorHealth = true
 				cfg.HealthCheckTimeout = 120 * time.Second
 				cfg.MaxFailingStreak = 5
+				cfg.HealthLogEntries = 0
+				cfg.StartupInventory = true
 				return cfg
 			}(),
 		},
 		{
 			name: "custom throttling settings",
 			envVars: map[string]string{
-				"NOTIDOCK_WINDOW_DURATION":       "30s",
-				"NOTIDOCK_EVENT_THRESHOLD":       "10",
-				"NOTIDOCK_NOTIFICATION_COOLDOWN": "5s",
+				"NOTIDOCK_WINDOW_DURATION":                "30s",
+				"NOTIDOCK_EVENT_THRESHOLD":                "10",
+				"NOTIDOCK_NOTIFICATION_COOLDOWN":          "5s",
+				"NOTIDOCK_THROTTLE_PAUSE_UNTIL_RECOVERED": "true",
 			},
 			expected: func() AppConfig {
 				cfg := getDefaultConfig()
 				cfg.WindowDuration = 30 * time.Second
 				cfg.EventThreshold = 10
 				cfg.NotificationCooldown = 5 * time.Second
+				cfg.PauseUntilRecovered = true
 				return cfg
 			}(),
 		},
+		{
+			name: "custom throttling strategies",
+			envVars: map[string]string{
+				"NOTIDOCK_THROTTLE_STRATEGIES":      "token_bucket, global",
+				"NOTIDOCK_THROTTLE_BURST":           "5",
+				"NOTIDOCK_THROTTLE_REFILL_INTERVAL": "1m",
+				"NOTIDOCK_GLOBAL_EVENT_THRESHOLD":   "20",
+				"NOTIDOCK_GLOBAL_WINDOW_DURATION":   "30s",
+			},
+			expected: func() AppConfig {
+				cfg := getDefaultConfig()
+				cfg.ThrottleStrategies = []string{ThrottleTokenBucket, ThrottleGlobal}
+				cfg.ThrottleBurst = 5
+				cfg.ThrottleRefillInterval = time.Minute
+				cfg.GlobalEventThreshold = 20
+				cfg.GlobalWindowDuration = 30 * time.Second
+				return cfg
+			}(),
+		},
+		{
+			name: "custom throttle key",
+			envVars: map[string]string{
+				"NOTIDOCK_THROTTLE_KEY": "compose_project, compose_service,action",
+			},
+			expected: func() AppConfig {
+				cfg := getDefaultConfig()
+				cfg.ThrottleKey = []string{ThrottleKeyProject, ThrottleKeyService, ThrottleKeyAction}
+				return cfg
+			}(),
+		},
+		{
+			name: "unknown throttle key part should use default",
+			envVars: map[string]string{
+				"NOTIDOCK_THROTTLE_KEY": "container,hostname",
+			},
+			expected: getDefaultConfig(),
+		},
+		{
+			name: "unknown throttling strategy should use default",
+			envVars: map[string]string{
+				"NOTIDOCK_THROTTLE_STRATEGIES": "sliding_window,leaky_bucket",
+			},
+			expected: getDefaultConfig(),
+		},
+		{
+			name: "custom timestamp settings",
+			envVars: map[string]string{
+				"NOTIDOCK_TIME_ZONE":     "Europe/Berlin",
+				"NOTIDOCK_TIME_FORMAT":   "DateTime",
+				"NOTIDOCK_RELATIVE_TIME": "true",
+			},
+			expected: func() AppConfig {
+				cfg := getDefaultConfig()
+				cfg.TimeZone = "Europe/Berlin"
+				cfg.TimeFormat = "DateTime"
+				cfg.RelativeTime = true
+				return cfg
+			}(),
+		},
+		{
+			name: "unknown time zone should use default",
+			envVars: map[string]string{
+				"NOTIDOCK_TIME_ZONE": "Mars/Olympus_Mons",
+			},
+			expected: getDefaultConfig(),
+		},
 		{
 			name: "invalid values should use defaults",
 			envVars: map[string]string{
@@ -78,6 +151,70 @@ func TestGetConfig(t *testing.T) {
 			},
 			expected: getDefaultConfig(),
 		},
+		{
+			name: "custom delivery queue settings",
+			envVars: map[string]string{
+				"NOTIDOCK_QUEUE_SIZE":     "500",
+				"NOTIDOCK_QUEUE_WORKERS":  "4",
+				"NOTIDOCK_QUEUE_OVERFLOW": "block",
+			},
+			expected: func() AppConfig {
+				cfg := getDefaultConfig()
+				cfg.QueueSize = 500
+				cfg.QueueWorkers = 4
+				cfg.QueueOverflow = "block"
+				return cfg
+			}(),
+		},
+		{
+			name: "invalid delivery queue settings should use defaults",
+			envVars: map[string]string{
+				"NOTIDOCK_QUEUE_SIZE":     "0",
+				"NOTIDOCK_QUEUE_OVERFLOW": "drop_everything",
+			},
+			expected: getDefaultConfig(),
+		},
+		{
+			name: "routes",
+			envVars: map[string]string{
+				"NOTIDOCK_ROUTES": `[
+					{"name": "critical", "severities": ["critical"], "notifiers": ["pushover"], "continue": true},
+					{"compose_projects": ["prod"], "labels": {"team": "payments"}, "notifiers": ["slack"]}
+				]`,
+			},
+			expected: func() AppConfig {
+				cfg := getDefaultConfig()
+				cfg.Routes = []RouteConfig{
+					{Name: "critical", Severities: []string{"critical"}, Notifiers: []string{"pushover"}, Continue: true},
+					{ComposeProjects: []string{"prod"}, Labels: map[string]string{"team": "payments"}, Notifiers: []string{"slack"}},
+				}
+				return cfg
+			}(),
+		},
+		{
+			name: "routes with unknown fields should use defaults",
+			envVars: map[string]string{
+				"NOTIDOCK_ROUTES": `[{"receiver": "slack"}]`,
+			},
+			expected: getDefaultConfig(),
+		},
+		{
+			name: "custom retry settings",
+			envVars: map[string]string{
+				"NOTIDOCK_RETRY_MAX_ATTEMPTS":    "5",
+				"NOTIDOCK_RETRY_INITIAL_BACKOFF": "500ms",
+				"NOTIDOCK_RETRY_MAX_BACKOFF":     "1m",
+				"NOTIDOCK_RETRY_TIMEOUT":         "5m",
+			},
+			expected: func() AppConfig {
+				cfg := getDefaultConfig()
+				cfg.RetryMaxAttempts = 5
+				cfg.RetryInitialBackoff = 500 * time.Millisecond
+				cfg.RetryMaxBackoff = time.Minute
+				cfg.RetryTimeout = 5 * time.Minute
+				return cfg
+			}(),
+		},
 		{
 			name: "custom docker socket",
 			envVars: map[string]string{
@@ -117,10 +254,12 @@ func TestParsers(t *testing.T) {
 		}{
 			{"true", true},
 			{"false", false},
-			{"True", false},
+			{"True", true},
+			{"TRUE", true},
 			{"False", false},
-			{"1", false},
+			{"1", true},
 			{"0", false},
+			{"yes", false},
 			{"", false},
 		}
 
@@ -212,16 +351,55 @@ func TestParsers(t *testing.T) {
 // Helper function to get default config for comparison
 func getDefaultConfig() AppConfig {
 	return AppConfig{
-		MonitorAllContainers: DefaultMonitorAll,
-		TrackedEvents:        strings.Split(DefaultTrackedEvents, ","),
-		TrackedExitCodes:     nil,
-		MonitorHealth:        DefaultMonitorHealth,
-		HealthCheckTimeout:   DefaultHealthTimeout,
-		MaxFailingStreak:     DefaultMaxFailingStreak,
-		DockerSocket:         DefaultDockerSocket,
-		WindowDuration:       DefaultWindowDuration,
-		EventThreshold:       DefaultEventThreshold,
-		NotificationCooldown: DefaultNotificationCooldown,
+		MonitorAllContainers:    DefaultMonitorAll,
+		TrackedEvents:           strings.Split(DefaultTrackedEvents, ","),
+		TrackedExitCodes:        nil,
+		MonitorHealth:           DefaultMonitorHealth,
+		HealthCheckTimeout:      DefaultHealthTimeout,
+		MaxFailingStreak:        DefaultMaxFailingStreak,
+		HealthLogEntries:        DefaultHealthLogEntries,
+		StartupInventory:        DefaultStartupInventory,
+		HealthFlapWindow:        DefaultHealthFlapWindow,
+		HealthFlapHigh:          DefaultHealthFlapHigh,
+		HealthFlapLow:           DefaultHealthFlapLow,
+		DockerSocket:            DefaultDockerSocket,
+		WindowDuration:          DefaultWindowDuration,
+		EventThreshold:          DefaultEventThreshold,
+		NotificationCooldown:    DefaultNotificationCooldown,
+		PauseUntilRecovered:     DefaultPauseUntilRecovered,
+		ThrottleStrategies:      []string{ThrottleSlidingWindow},
+		ThrottleKey:             []string{ThrottleKeyContainer, ThrottleKeyImage},
+		ThrottleBurst:           DefaultThrottleBurst,
+		ThrottleRefillInterval:  DefaultThrottleRefill,
+		GlobalEventThreshold:    DefaultGlobalThreshold,
+		GlobalWindowDuration:    DefaultGlobalWindow,
+		CrashLoopThreshold:      DefaultCrashLoopThreshold,
+		CrashLoopWindow:         DefaultCrashLoopWindow,
+		CrashLoopStable:         DefaultCrashLoopStable,
+		CorrelationWindow:       DefaultCorrelationWindow,
+		QueueSize:               DefaultQueueSize,
+		QueueWorkers:            DefaultQueueWorkers,
+		QueueOverflow:           DefaultQueueOverflow,
+		Routes:                  nil,
+		Templates:               nil,
+		CircuitFailureThreshold: DefaultCircuitThreshold,
+		CircuitCooldown:         DefaultCircuitCooldown,
+		RetryMaxAttempts:        DefaultRetryMaxAttempts,
+		RetryInitialBackoff:     DefaultRetryInitialBackoff,
+		RetryMaxBackoff:         DefaultRetryMaxBackoff,
+		RetryTimeout:            DefaultRetryTimeout,
+		StateDir:                DefaultStateDir,
+		DeadLetterInterval:      DefaultDeadLetterInterval,
+		DeadLetterMax:           DefaultDeadLetterMax,
+		Reconcile:               DefaultReconcile,
+		StatusAddr:              DefaultStatusAddr,
+		TimeZone:                DefaultTimeZone,
+		TimeFormat:              DefaultTimeFormat,
+		RelativeTime:            DefaultRelativeTime,
+		ConfigWatchInterval:     DefaultConfigWatchInterval,
+		StrictConfig:            DefaultStrictConfig,
+		Notifiers:               nil,
+		ConfigFile:              "",
 	}
 }
 
@@ -274,3 +452,92 @@ func TestEnvOrDefault(t *testing.T) {
 		})
 	}
 }
+
+func TestLookupEnv(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "slack_webhook")
+	if err := os.WriteFile(secret, []byte("https://hooks.slack.com/services/T000/B000/XXXX\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "value",
+			env:      map[string]string{"NOTIDOCK_TEST": "value"},
+			expected: "value",
+		},
+		{
+			name:     "file",
+			env:      map[string]string{"NOTIDOCK_TEST_FILE": secret},
+			expected: "https://hooks.slack.com/services/T000/B000/XXXX",
+		},
+		{
+			name:    "missing file",
+			env:     map[string]string{"NOTIDOCK_TEST_FILE": filepath.Join(t.TempDir(), "missing")},
+			wantErr: true,
+		},
+		{
+			name:    "both set",
+			env:     map[string]string{"NOTIDOCK_TEST": "value", "NOTIDOCK_TEST_FILE": secret},
+			wantErr: true,
+		},
+		{
+			name:     "not set",
+			env:      map[string]string{},
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Clearenv()
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			got, err := LookupEnv("NOTIDOCK_TEST")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LookupEnv() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("LookupEnv() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetConfig_FileVariants(t *testing.T) {
+	os.Clearenv()
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	t.Setenv("NOTIDOCK_QUEUE_SIZE_FILE", write("queue_size", "50\n"))
+	t.Setenv("NOTIDOCK_STATUS_ADDR_FILE", write("status_addr", "not-a-secret"))
+	t.Setenv("NOTIDOCK_STATUS_ADDR", ":9090")
+	t.Setenv("NOTIDOCK_EVENT_THRESHOLD_FILE", write("event_threshold", "s3cr3t"))
+
+	cfg, errs := applyEnv(defaultConfig())
+	if cfg.QueueSize != 50 {
+		t.Errorf("QueueSize = %d, want 50", cfg.QueueSize)
+	}
+	if cfg.StatusAddr != DefaultStatusAddr {
+		t.Errorf("StatusAddr = %q, want the default when both variants are set", cfg.StatusAddr)
+	}
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %v", errs)
+	}
+	for _, err := range errs {
+		if strings.Contains(err.Error(), "s3cr3t") {
+			t.Errorf("error %q must not contain the file contents", err)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Change describes a setting that differs between two configurations
+type Change struct {
+	// Key is the configuration file key of the setting
+	Key string
+	Old any
+	New any
+}
+
+// Diff returns the settings that differ between c and other
+func (c AppConfig) Diff(other AppConfig) []Change {
+	var changes []Change
+	oldValue, newValue := reflect.ValueOf(c), reflect.ValueOf(other)
+	t := oldValue.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		o, n := oldValue.Field(i).Interface(), newValue.Field(i).Interface()
+		if !reflect.DeepEqual(o, n) {
+			changes = append(changes, Change{Key: key, Old: o, New: n})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,303 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// NotifierConfig defines a notifier in the configuration file. Exactly one
+// of the notifier type sections must be set.
+type NotifierConfig struct {
+	// Name identifies the notifier in routes and container labels
+	Name     string          `yaml:"name"`
+	Slack    *SlackConfig    `yaml:"slack,omitempty"`
+	NATS     *NATSConfig     `yaml:"nats,omitempty"`
+	Redis    *RedisConfig    `yaml:"redis,omitempty"`
+	Pushover *PushoverConfig `yaml:"pushover,omitempty"`
+	Webhook  *WebhookConfig  `yaml:"webhook,omitempty"`
+}
+
+type SlackConfig struct {
+	WebhookURL      string   `yaml:"webhook_url"`
+	DateTokens      bool     `yaml:"date_tokens"`
+	AllowedChannels []string `yaml:"allowed_channels"`
+}
+
+type NATSConfig struct {
+	URL       string `yaml:"url"`
+	Subject   string `yaml:"subject"`
+	Token     string `yaml:"token"`
+	JetStream bool   `yaml:"jetstream"`
+}
+
+type RedisConfig struct {
+	URL    string `yaml:"url"`
+	Stream string `yaml:"stream"`
+	// MaxLen is nil when the default applies, 0 disables trimming
+	MaxLen *int `yaml:"maxlen"`
+}
+
+type PushoverConfig struct {
+	Token  string            `yaml:"token"`
+	User   string            `yaml:"user"`
+	Device string            `yaml:"device"`
+	Sounds map[string]string `yaml:"sounds"`
+	Retry  time.Duration     `yaml:"retry"`
+	Expire time.Duration     `yaml:"expire"`
+}
+
+type WebhookConfig struct {
+	URL          string   `yaml:"url"`
+	AllowedHosts []string `yaml:"allowed_hosts"`
+}
+
+// Type returns the notifier type, or "" when no type section is set
+func (n NotifierConfig) Type() string {
+	types := n.types()
+	if len(types) == 0 {
+		return ""
+	}
+	return types[0]
+}
+
+func (n NotifierConfig) types() []string {
+	var types []string
+	if n.Slack != nil {
+		types = append(types, "slack")
+	}
+	if n.NATS != nil {
+		types = append(types, "nats")
+	}
+	if n.Redis != nil {
+		types = append(types, "redis")
+	}
+	if n.Pushover != nil {
+		types = append(types, "pushover")
+	}
+	if n.Webhook != nil {
+		types = append(types, "webhook")
+	}
+	return types
+}
+
+// Route severities
+var routeSeverities = []string{"info", "warning", "critical"}
+
+// errDeprecated marks settings that are accepted but ignored
+var errDeprecated = errors.New("no longer used and will be removed in a future release")
+
+// Container events that can be tracked
+var dockerEvents = []string{
+	"attach", "commit", "copy", "create", "destroy", "detach", "die",
+	"exec_create", "exec_detach", "exec_die", "exec_start", "export",
+	"health_status", "kill", "oom", "pause", "rename", "resize", "restart",
+	"start", "stop", "top", "unpause", "update",
+}
+
+// Load returns the application configuration: the defaults, overridden by
+// the YAML file named in NOTIDOCK_CONFIG_FILE, if set, overridden in turn
+// by environment variables. Unknown keys and invalid values in the file are
+// reported as errors. Environment variables that cannot be parsed and
+// questionable values, such as unknown event names, are logged and ignored
+// unless strict_config is set.
+func Load() (AppConfig, error) {
+	return load(false)
+}
+
+// LoadStrict is like Load, but reports every invalid setting as an error
+// as if strict_config was set
+func LoadStrict() (AppConfig, error) {
+	return load(true)
+}
+
+func load(strict bool) (AppConfig, error) {
+	cfg := defaultConfig()
+	path, err := LookupEnv(EnvPrefix + KeyConfigFile)
+	if err != nil {
+		return AppConfig{}, err
+	}
+	if path != "" {
+		if err := loadFile(path, &cfg); err != nil {
+			return AppConfig{}, err
+		}
+		cfg.ConfigFile = path
+	}
+
+	cfg, errs := applyEnv(cfg)
+	errs = append(errs, cfg.checkValues()...)
+	cfg.warnDeprecated()
+	if !strict && !cfg.StrictConfig {
+		warnInvalid(errs)
+		errs = nil
+	}
+	if err := cfg.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return AppConfig{}, err
+	}
+	return cfg, nil
+}
+
+func loadFile(path string, cfg *AppConfig) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	defer f.Close()
+
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate checks the configuration and returns every problem found,
+// named by the configuration file key
+func (c AppConfig) Validate() error {
+	var errs []error
+	check := func(key string, err error) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+
+	check("queue_size", positive(c.QueueSize))
+	check("queue_workers", positive(c.QueueWorkers))
+	check("queue_overflow", oneOf(queueOverflowPolicies, c.QueueOverflow))
+	check("time_zone", timeZone(c.TimeZone))
+	check("health_log_entries", nonNegative(c.HealthLogEntries))
+	check("health_flap_window", positiveDuration(c.HealthFlapWindow))
+	check("health_flap_high", nonNegative(c.HealthFlapHigh))
+	check("health_flap_low", nonNegative(c.HealthFlapLow))
+	if c.HealthFlapHigh > 0 && c.HealthFlapLow >= c.HealthFlapHigh {
+		check("health_flap_low", fmt.Errorf("must be less than health_flap_high (%d), got %d", c.HealthFlapHigh, c.HealthFlapLow))
+	}
+	check("crashloop_threshold", nonNegative(c.CrashLoopThreshold))
+	check("crashloop_window", positiveDuration(c.CrashLoopWindow))
+	check("crashloop_stable", positiveDuration(c.CrashLoopStable))
+	check("correlation_window", nonNegativeDuration(c.CorrelationWindow))
+	for _, strategy := range c.ThrottleStrategies {
+		check("throttle_strategies", oneOf(throttleStrategies, strategy))
+	}
+	for _, part := range c.ThrottleKey {
+		check("throttle_key", oneOf(throttleKeyParts, part))
+	}
+	check("throttle_burst", positive(c.ThrottleBurst))
+	check("throttle_refill_interval", positiveDuration(c.ThrottleRefillInterval))
+	check("global_event_threshold", nonNegative(c.GlobalEventThreshold))
+	check("global_window_duration", positiveDuration(c.GlobalWindowDuration))
+	check("circuit_failure_threshold", nonNegative(c.CircuitFailureThreshold))
+	check("circuit_cooldown", positiveDuration(c.CircuitCooldown))
+	check("retry_max_attempts", positive(c.RetryMaxAttempts))
+	check("deadletter_interval", positiveDuration(c.DeadLetterInterval))
+	check("deadletter_max", positive(c.DeadLetterMax))
+
+	for i, r := range c.Routes {
+		for _, s := range r.Severities {
+			check(fmt.Sprintf("routes[%d].severities", i), oneOf(routeSeverities, s))
+		}
+	}
+
+	names := map[string]bool{}
+	for i, n := range c.Notifiers {
+		key := fmt.Sprintf("notifiers[%d]", i)
+		if n.Name != "" {
+			key = fmt.Sprintf("notifiers[%s]", n.Name)
+		}
+		switch {
+		case n.Name == "":
+			check(key, errors.New("name is required"))
+		case names[n.Name]:
+			check(key, errors.New("duplicate notifier name"))
+		}
+		names[n.Name] = true
+
+		switch types := n.types(); len(types) {
+		case 0:
+			check(key, errors.New("one of slack, nats, redis, pushover or webhook must be set"))
+		case 1:
+			check(key, n.validate())
+		default:
+			check(key, fmt.Errorf("only one notifier type may be set, got %s", strings.Join(types, ", ")))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkValues returns the settings that notidock can run with, but that
+// are most likely mistakes
+func (c AppConfig) checkValues() []error {
+	var errs []error
+	check := func(key string, err error) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+
+	for _, event := range c.TrackedEvents {
+		if !slices.Contains(dockerEvents, strings.TrimSpace(event)) {
+			check("tracked_events", fmt.Errorf("unknown event %q", event))
+		}
+	}
+	for _, code := range c.TrackedExitCodes {
+		if _, err := strconv.Atoi(code); err != nil {
+			check("tracked_exitcodes", fmt.Errorf("must be a number, got %q", code))
+		}
+	}
+	if !strings.HasPrefix(c.DockerSocket, "unix://") && !strings.HasPrefix(c.DockerSocket, "tcp://") {
+		check("docker_socket", fmt.Errorf("must start with unix:// or tcp://, got %q", c.DockerSocket))
+	}
+	check("window_duration", positiveDuration(c.WindowDuration))
+	check("event_threshold", nonNegative(c.EventThreshold))
+	check("notification_cooldown", nonNegativeDuration(c.NotificationCooldown))
+	check("retry_initial_backoff", nonNegativeDuration(c.RetryInitialBackoff))
+	check("retry_max_backoff", nonNegativeDuration(c.RetryMaxBackoff))
+	check("retry_timeout", nonNegativeDuration(c.RetryTimeout))
+	check("config_watch_interval", nonNegativeDuration(c.ConfigWatchInterval))
+	return errs
+}
+
+// warnDeprecated logs the settings that are accepted but ignored. They are
+// never errors, not even with strict_config, so that existing configurations
+// keep working after an upgrade.
+func (c AppConfig) warnDeprecated() {
+	// Health is followed through health_status events since these settings
+	// were used for polling
+	if c.HealthCheckTimeout != DefaultHealthTimeout {
+		slog.Warn("deprecated configuration value", "key", "health_timeout", "error", errDeprecated)
+	}
+	if c.MaxFailingStreak != DefaultMaxFailingStreak {
+		slog.Warn("deprecated configuration value", "key", "max_failing_streak", "error", errDeprecated)
+	}
+}
+
+func (n NotifierConfig) validate() error {
+	switch {
+	case n.Slack != nil && n.Slack.WebhookURL == "":
+		return errors.New("slack.webhook_url is required")
+	case n.NATS != nil && n.NATS.URL == "":
+		return errors.New("nats.url is required")
+	case n.Redis != nil && n.Redis.URL == "":
+		return errors.New("redis.url is required")
+	case n.Redis != nil && n.Redis.MaxLen != nil && *n.Redis.MaxLen < 0:
+		return fmt.Errorf("redis.maxlen: must not be negative, got %d", *n.Redis.MaxLen)
+	case n.Pushover != nil && (n.Pushover.Token == "" || n.Pushover.User == ""):
+		return errors.New("pushover.token and pushover.user are required")
+	case n.Webhook != nil && n.Webhook.URL == "" && len(n.Webhook.AllowedHosts) == 0:
+		return errors.New("webhook.url or webhook.allowed_hosts is required")
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "notidock.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(EnvPrefix+KeyConfigFile, path)
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	os.Clearenv()
+	path := writeConfigFile(t, `
+monitor_all: true
+tracked_events: [start, die, oom]
+tracked_exitcodes: [1, 137]
+monitor_health: true
+queue_overflow: block
+notifiers:
+  - name: alerts
+    slack:
+      webhook_url: https://hooks.slack.com/services/alerts
+  - name: events
+    redis:
+      url: redis://redis:6379
+      maxlen: 0
+routes:
+  - name: prod
+    compose_projects: [prod]
+    notifiers: [alerts]
+`)
+	t.Setenv("NOTIDOCK_QUEUE_OVERFLOW", "drop_newest")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	expected := getDefaultConfig()
+	expected.ConfigFile = path
+	expected.MonitorAllContainers = true
+	expected.TrackedEvents = []string{"start", "die", "oom"}
+	expected.TrackedExitCodes = []string{"1", "137"}
+	expected.MonitorHealth = true
+	expected.QueueOverflow = "drop_newest"
+	maxLen := 0
+	expected.Notifiers = []NotifierConfig{
+		{Name: "alerts", Slack: &SlackConfig{WebhookURL: "https://hooks.slack.com/services/alerts"}},
+		{Name: "events", Redis: &RedisConfig{URL: "redis://redis:6379", MaxLen: &maxLen}},
+	}
+	expected.Routes = []RouteConfig{
+		{Name: "prod", ComposeProjects: []string{"prod"}, Notifiers: []string{"alerts"}},
+	}
+
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Errorf("Load() = %+v, want %+v", cfg, expected)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{
+			name:    "unknown key",
+			content: "queue_sise: 10\n",
+			want:    []string{"line 1: field queue_sise not found"},
+		},
+		{
+			name:    "wrong type",
+			content: "health_timeout: 60\n",
+			want:    []string{"line 1"},
+		},
+		{
+			name: "invalid values",
+			content: `
+queue_size: 0
+queue_overflow: drop_everything
+time_zone: Mars/Olympus_Mons
+health_flap_high: 3
+health_flap_low: 3
+correlation_window: -1s
+throttle_strategies: [sliding_window, leaky_bucket]
+throttle_key: [compose_service, hostname]
+routes:
+  - severities: [urgent]
+    notifiers: [alerts]
+`,
+			want: []string{
+				"queue_size: must be greater than 0, got 0",
+				`queue_overflow: must be one of drop_oldest, drop_newest, block, got "drop_everything"`,
+				`time_zone: unknown time zone "Mars/Olympus_Mons"`,
+				"health_flap_low: must be less than health_flap_high (3), got 3",
+				"correlation_window: must not be negative, got -1s",
+				`throttle_strategies: must be one of sliding_window, token_bucket, global, got "leaky_bucket"`,
+				`throttle_key: must be one of container, image, compose_service, compose_project, action, got "hostname"`,
+				`routes[0].severities: must be one of info, warning, critical, got "urgent"`,
+			},
+		},
+		{
+			name: "invalid notifiers",
+			content: `
+notifiers:
+  - slack:
+      webhook_url: https://hooks.slack.com/services/a
+  - name: alerts
+  - name: paging
+    pushover:
+      token: app-token
+    webhook:
+      url: https://hooks.example.com
+`,
+			want: []string{
+				"notifiers[0]: name is required",
+				"notifiers[alerts]: one of slack, nats, redis, pushover or webhook must be set",
+				"notifiers[paging]: only one notifier type may be set, got pushover, webhook",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Clearenv()
+			writeConfigFile(t, tt.content)
+
+			_, err := Load()
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoad_WithoutFile(t *testing.T) {
+	os.Clearenv()
+	t.Setenv("NOTIDOCK_QUEUE_SIZE", "50")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	expected := getDefaultConfig()
+	expected.QueueSize = 50
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Errorf("Load() = %+v, want %+v", cfg, expected)
+	}
+}
+
+func TestAppConfig_Diff(t *testing.T) {
+	current := getDefaultConfig()
+	next := getDefaultConfig()
+	next.EventThreshold = 5
+	next.ConfigFile = "/etc/notidock.yaml"
+	next.Notifiers = []NotifierConfig{{Name: "alerts", Slack: &SlackConfig{WebhookURL: "https://hooks.slack.com/services/a"}}}
+
+	changes := current.Diff(next)
+	expected := []Change{
+		{Key: "event_threshold", Old: DefaultEventThreshold, New: 5},
+		{Key: "notifiers", Old: []NotifierConfig(nil), New: next.Notifiers},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Diff() = %+v, want %+v", changes, expected)
+	}
+	if changes := next.Diff(next); len(changes) != 0 {
+		t.Errorf("Diff() of equal configurations = %+v", changes)
+	}
+}
+
+func TestLoad_Strict(t *testing.T) {
+	os.Clearenv()
+	writeConfigFile(t, `
+tracked_events: [start, dei]
+tracked_exitcodes: [1, oom]
+docker_socket: /var/run/docker.sock
+max_failing_streak: 5
+`)
+	t.Setenv("NOTIDOCK_MONITOR_ALL", "yes")
+	t.Setenv("NOTIDOCK_HEALTH_TIMEOUT", "5 minutes")
+
+	if _, err := Load(); err != nil {
+		t.Fatalf("Load() without strict_config should only warn, got %v", err)
+	}
+
+	_, err := LoadStrict()
+	if err == nil {
+		t.Fatal("LoadStrict() expected an error")
+	}
+	for _, want := range []string{
+		`NOTIDOCK_MONITOR_ALL: invalid value "yes"`,
+		`NOTIDOCK_HEALTH_TIMEOUT: invalid value "5 minutes"`,
+		`tracked_events: unknown event "dei"`,
+		`tracked_exitcodes: must be a number, got "oom"`,
+		`docker_socket: must start with unix:// or tcp://, got "/var/run/docker.sock"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+
+	t.Setenv("NOTIDOCK_STRICT_CONFIG", "true")
+	if _, err := Load(); err == nil {
+		t.Error("Load() with NOTIDOCK_STRICT_CONFIG expected an error")
+	}
+}
+
+func TestLoadStrict_Deprecated(t *testing.T) {
+	os.Clearenv()
+	writeConfigFile(t, `
+strict_config: true
+max_failing_streak: 5
+`)
+	t.Setenv("NOTIDOCK_HEALTH_TIMEOUT", "5m")
+
+	if _, err := LoadStrict(); err != nil {
+		t.Fatalf("LoadStrict() with deprecated settings should only warn, got %v", err)
+	}
+	if _, err := Load(); err != nil {
+		t.Fatalf("Load() with strict_config and deprecated settings should only warn, got %v", err)
+	}
+}
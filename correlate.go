@@ -0,0 +1,195 @@
+package main
+
+import (
+	"maps"
+	"notidock/config"
+	"notidock/notification"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// correlationCheckInterval is how often held events are checked for their
+// window having passed
+const correlationCheckInterval = 250 * time.Millisecond
+
+// correlatedActions are the actions Docker emits together when a container
+// is stopped, killed or removed, in the order of precedence for the action
+// of the merged notification
+var correlatedActions = []string{"oom", "die", "kill", "stop", "destroy"}
+
+// signalNames names the signals containers are usually stopped with
+var signalNames = map[string]string{
+	"1":  "SIGHUP",
+	"2":  "SIGINT",
+	"3":  "SIGQUIT",
+	"6":  "SIGABRT",
+	"9":  "SIGKILL",
+	"10": "SIGUSR1",
+	"12": "SIGUSR2",
+	"15": "SIGTERM",
+}
+
+// eventCorrelator merges the notifications Docker produces for a single
+// action, such as the kill, die and stop of docker stop, into one. Events
+// of a container are held until none followed for the window, or a destroy
+// ends the container. Other events are delivered at once, after the events
+// held for their container so the order is kept. It is used from the main
+// loop only.
+type eventCorrelator struct {
+	window    time.Duration
+	incidents map[string]*incident
+}
+
+// incident is the events of a container held for correlation
+type incident struct {
+	events   []notification.Event
+	deadline time.Time
+}
+
+func newEventCorrelator(cfg config.AppConfig) *eventCorrelator {
+	c := &eventCorrelator{incidents: make(map[string]*incident)}
+	c.SetLimits(cfg)
+	return c
+}
+
+// SetLimits applies a new correlation window. Held events keep their
+// deadline.
+func (c *eventCorrelator) SetLimits(cfg config.AppConfig) {
+	c.window = cfg.CorrelationWindow
+}
+
+// Add passes the notification of a container event through correlation
+// and returns the notifications to send now
+func (c *eventCorrelator) Add(containerID string, event notification.Event, now time.Time) []notification.Event {
+	pending := c.incidents[containerID]
+	var ready []notification.Event
+	if pending != nil && (!slices.Contains(correlatedActions, event.Action) || now.After(pending.deadline)) {
+		ready = append(ready, pending.merge())
+		delete(c.incidents, containerID)
+		pending = nil
+	}
+
+	if c.window <= 0 || !slices.Contains(correlatedActions, event.Action) {
+		return append(ready, event)
+	}
+	if pending == nil {
+		pending = &incident{}
+		c.incidents[containerID] = pending
+	}
+	pending.events = append(pending.events, event)
+	pending.deadline = now.Add(c.window)
+
+	// Nothing follows the removal of a container
+	if event.Action == "destroy" {
+		ready = append(ready, pending.merge())
+		delete(c.incidents, containerID)
+	}
+	return ready
+}
+
+// Flush returns the merged notifications of the containers whose window
+// passed before now, oldest first
+func (c *eventCorrelator) Flush(now time.Time) []notification.Event {
+	return c.flush(func(pending *incident) bool { return !now.Before(pending.deadline) })
+}
+
+// FlushAll returns the merged notifications of every held event, for
+// shutdown
+func (c *eventCorrelator) FlushAll() []notification.Event {
+	return c.flush(func(*incident) bool { return true })
+}
+
+func (c *eventCorrelator) flush(due func(*incident) bool) []notification.Event {
+	var ready []notification.Event
+	for id, pending := range c.incidents {
+		if due(pending) {
+			ready = append(ready, pending.merge())
+			delete(c.incidents, id)
+		}
+	}
+	slices.SortFunc(ready, func(a, b notification.Event) int {
+		return a.Timestamp.Compare(b.Timestamp)
+	})
+	return ready
+}
+
+// merge combines the held events into one notification. A single event is
+// returned unchanged. Otherwise the notification takes the action that
+// tells the most, the labels of every event, and describes the incident in
+// the actions and incident labels, such as "stopped by SIGTERM, exit 143
+// after 2h 3m".
+func (i *incident) merge() notification.Event {
+	if len(i.events) == 1 {
+		return i.events[0]
+	}
+
+	var actions []string
+	labels := make(map[string]string)
+	for _, e := range i.events {
+		actions = append(actions, e.Action)
+		maps.Copy(labels, e.Labels)
+	}
+
+	merged := i.events[0]
+	for _, action := range correlatedActions {
+		if j := slices.Index(actions, action); j >= 0 {
+			merged = i.events[j]
+			break
+		}
+	}
+	// Exit details only come with the die event
+	if j := slices.Index(actions, "die"); j >= 0 {
+		die := i.events[j]
+		merged.ExitCode = die.ExitCode
+		merged.ExitStatus = die.ExitStatus
+		merged.ExecDuration = die.ExecDuration
+		merged.Runtime = die.Runtime
+	}
+	merged.Timestamp = i.events[len(i.events)-1].Timestamp
+	merged.Labels = labels
+	merged.Labels["actions"] = strings.Join(actions, ",")
+	merged.Labels["incident"] = describeIncident(actions, merged)
+	return merged
+}
+
+// describeIncident summarizes what happened to the container, for example
+// "stopped by SIGTERM, exit 143 after 2h 3m"
+func describeIncident(actions []string, event notification.Event) string {
+	var b strings.Builder
+	switch {
+	case slices.Contains(actions, "destroy"):
+		b.WriteString("removed")
+	case slices.Contains(actions, "stop"):
+		b.WriteString("stopped")
+	case slices.Contains(actions, "oom"):
+		b.WriteString("killed by the OOM killer")
+	case slices.Contains(actions, "kill"):
+		b.WriteString("killed")
+	default:
+		b.WriteString("exited")
+	}
+	if signal := event.Labels["signal"]; signal != "" && slices.Contains(actions, "kill") && !slices.Contains(actions, "oom") {
+		b.WriteString(" by " + signalName(signal))
+	}
+	if event.ExitStatus != "" {
+		b.WriteString(", exit " + event.ExitStatus)
+	}
+	if event.Runtime > 0 {
+		b.WriteString(" after " + notification.HumanDuration(event.Runtime))
+	}
+	return b.String()
+}
+
+// signalName returns the name of a signal given by number, as in the
+// signal attribute of kill events
+func signalName(signal string) string {
+	if name, ok := signalNames[signal]; ok {
+		return name
+	}
+	if _, err := strconv.Atoi(signal); err == nil {
+		return "signal " + signal
+	}
+	return signal
+}
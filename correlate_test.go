@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"notidock/config"
+	"notidock/notification"
+	"testing"
+	"time"
+)
+
+func TestEventCorrelator_Stop(t *testing.T) {
+	c := newEventCorrelator(config.AppConfig{CorrelationWindow: 2 * time.Second})
+	at := time.Date(2024, 12, 14, 17, 0, 0, 0, time.UTC)
+
+	for _, event := range []notification.Event{
+		{ContainerName: "web", Action: "kill", Timestamp: at, Labels: map[string]string{"signal": "15"}},
+		{ContainerName: "web", Action: "die", Timestamp: at, Labels: map[string]string{"exitCode": "143"},
+			ExitCode: FormatExitCode("143"), ExitStatus: "143", Runtime: 2*time.Hour + 3*time.Minute},
+		{ContainerName: "web", Action: "stop", Timestamp: at},
+	} {
+		if ready := c.Add("w", event, at); len(ready) != 0 {
+			t.Fatalf("%s sent before the window passed: %+v", event.Action, ready)
+		}
+		at = at.Add(time.Second)
+	}
+
+	if ready := c.Flush(at); len(ready) != 0 {
+		t.Fatalf("sent before the window passed: %+v", ready)
+	}
+	ready := c.Flush(at.Add(time.Second))
+	if len(ready) != 1 {
+		t.Fatalf("Flush() returned %d notifications, want 1", len(ready))
+	}
+	merged := ready[0]
+	if merged.Action != "die" || merged.ExitStatus != "143" {
+		t.Errorf("merged = %+v, want the die with exit 143", merged)
+	}
+	if got := merged.Labels["actions"]; got != "kill,die,stop" {
+		t.Errorf("actions = %q, want kill,die,stop", got)
+	}
+	if got, want := merged.Labels["incident"], "stopped by SIGTERM, exit 143 after 2h 3m"; got != want {
+		t.Errorf("incident = %q, want %q", got, want)
+	}
+	if len(c.incidents) != 0 {
+		t.Errorf("correlator still holds %d incidents", len(c.incidents))
+	}
+}
+
+func TestEventCorrelator_KeepsOrder(t *testing.T) {
+	c := newEventCorrelator(config.AppConfig{CorrelationWindow: time.Minute})
+	at := time.Now()
+
+	c.Add("w", notification.Event{Action: "kill", Labels: map[string]string{"signal": "9"}}, at)
+	c.Add("w", notification.Event{Action: "die", ExitStatus: "137"}, at)
+	// Unrelated and other containers' events are not held
+	if ready := c.Add("a", notification.Event{Action: "start"}, at); len(ready) != 1 {
+		t.Fatalf("start of another container returned %+v", ready)
+	}
+	ready := c.Add("w", notification.Event{Action: "start"}, at)
+	if len(ready) != 2 || ready[0].Action != "die" || ready[1].Action != "start" {
+		t.Fatalf("start returned %+v, want the held die then the start", ready)
+	}
+	if got, want := ready[0].Labels["incident"], "killed by SIGKILL, exit 137"; got != want {
+		t.Errorf("incident = %q, want %q", got, want)
+	}
+}
+
+func TestEventCorrelator_Destroy(t *testing.T) {
+	c := newEventCorrelator(config.AppConfig{CorrelationWindow: time.Minute})
+	at := time.Now()
+
+	c.Add("w", notification.Event{Action: "kill", Labels: map[string]string{"signal": "9"}}, at)
+	c.Add("w", notification.Event{Action: "die", ExitStatus: "137"}, at)
+	ready := c.Add("w", notification.Event{Action: "destroy"}, at)
+	if len(ready) != 1 || ready[0].Labels["incident"] != "removed by SIGKILL, exit 137" {
+		t.Fatalf("destroy returned %+v, want the removal at once", ready)
+	}
+
+	// A lone event is sent as it is once the window passed
+	c.Add("x", notification.Event{Action: "die", ExitStatus: "1"}, at)
+	ready = c.Flush(at.Add(time.Minute))
+	if len(ready) != 1 || ready[0].Labels != nil {
+		t.Errorf("Flush() = %+v, want the die unchanged", ready)
+	}
+}
+
+func TestEventCorrelator_Disabled(t *testing.T) {
+	c := newEventCorrelator(config.AppConfig{})
+	if ready := c.Add("w", notification.Event{Action: "kill"}, time.Now()); len(ready) != 1 {
+		t.Errorf("Add() = %+v, want the kill at once", ready)
+	}
+}
+
+func TestHandleContainerEvent_Correlated(t *testing.T) {
+	cfg := getTestConfig()
+	cfg.TrackedEvents = []string{"kill", "die", "stop"}
+	cfg.CorrelationWindow = time.Minute
+	cfg.EventThreshold = 2
+
+	recorder := &recordingNotifier{}
+	manager := notification.NewManager(notification.ManagerOptions{}, recorder)
+	throttler := NewNotificationThrottler(cfg)
+	health := newHealthTracker(cfg, nil)
+	crashLoops := newCrashLoopDetector(cfg)
+	correlator := newEventCorrelator(cfg)
+
+	at := time.Now()
+	for i := 0; i < 2; i++ {
+		for _, e := range []struct {
+			action     string
+			attributes map[string]string
+		}{
+			{"kill", map[string]string{"name": "web", "signal": "15"}},
+			{"die", map[string]string{"name": "web", "exitCode": "143", "execDuration": "60"}},
+			{"stop", map[string]string{"name": "web"}},
+		} {
+			event := lifecycleEvent("w", e.action, at, e.attributes)
+			handleContainerEvent(context.Background(), event, cfg, manager, throttler, health, crashLoops, correlator)
+		}
+		for _, event := range correlator.Flush(time.Now().Add(time.Hour)) {
+			deliver(context.Background(), event, manager, throttler)
+		}
+	}
+	manager.Close(context.Background())
+
+	// Each stop counts once towards the throttling threshold
+	if len(recorder.events) != 2 {
+		t.Fatalf("notified %d events, want 2", len(recorder.events))
+	}
+	if got, want := recorder.events[0].Labels["incident"], "stopped by SIGTERM, exit 143 after 1m 0s"; got != want {
+		t.Errorf("incident = %q, want %q", got, want)
+	}
+}
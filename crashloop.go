@@ -0,0 +1,152 @@
+package main
+
+import (
+	"maps"
+	"notidock/config"
+	"notidock/notification"
+	"strconv"
+	"time"
+)
+
+// detectorCheckInterval is how often crash loops, flapping and throttled
+// containers are checked for having settled
+const detectorCheckInterval = 10 * time.Second
+
+// crashLoopDetector recognises containers that keep exiting and being
+// restarted. Once a container has died threshold times within the window a
+// single crash_loop alert replaces its die and start notifications, until it
+// stays up for the stable period. It is used from the main loop only.
+type crashLoopDetector struct {
+	threshold  int
+	window     time.Duration
+	stable     time.Duration
+	containers map[string]*crashLoop
+}
+
+type crashLoop struct {
+	attributes map[string]string
+	// dies are the times the container died within the window
+	dies     []time.Time
+	looping  bool
+	restarts int
+	lastExit string
+	// runningSince is when the container last started, zero while it is
+	// not running
+	runningSince time.Time
+}
+
+func newCrashLoopDetector(cfg config.AppConfig) *crashLoopDetector {
+	d := &crashLoopDetector{containers: make(map[string]*crashLoop)}
+	d.SetLimits(cfg)
+	return d
+}
+
+// SetLimits applies new crash loop settings, keeping the state of every
+// container
+func (d *crashLoopDetector) SetLimits(cfg config.AppConfig) {
+	d.threshold = cfg.CrashLoopThreshold
+	d.window = cfg.CrashLoopWindow
+	d.stable = cfg.CrashLoopStable
+}
+
+// Observe records a lifecycle event of a monitored container. It returns
+// the alert to send when the event starts a crash loop, and whether the
+// event is part of a crash loop and should not be notified on its own.
+func (d *crashLoopDetector) Observe(event Event) (*notification.Event, bool) {
+	if d.threshold <= 0 {
+		return nil, false
+	}
+
+	id := event.Actor.ID
+	at := eventTime(event)
+	loop := d.containers[id]
+	switch event.Action {
+	case "die":
+		if loop == nil {
+			loop = &crashLoop{}
+			d.containers[id] = loop
+		}
+		loop.attributes = event.Actor.Attributes
+		loop.lastExit = event.Actor.Attributes["exitCode"]
+		loop.runningSince = time.Time{}
+		loop.dies = append(loop.dies, at)
+		loop.prune(at.Add(-d.window))
+
+		if loop.looping {
+			loop.restarts++
+			return nil, true
+		}
+		if len(loop.dies) < d.threshold {
+			return nil, false
+		}
+		loop.looping = true
+		loop.restarts = len(loop.dies)
+		alert := loop.event("crash_loop", at)
+		alert.Labels["window"] = d.window.String()
+		return &alert, true
+	case "start", "restart":
+		if loop == nil {
+			return nil, false
+		}
+		loop.runningSince = at
+		return nil, loop.looping
+	case "destroy":
+		delete(d.containers, id)
+	}
+	return nil, false
+}
+
+// Check returns the crash_loop_resolved events of the looping containers
+// that have been running for the stable period, and forgets the containers
+// that have not died within the window
+func (d *crashLoopDetector) Check(now time.Time) []notification.Event {
+	var resolved []notification.Event
+	for id, loop := range d.containers {
+		if !loop.looping {
+			loop.prune(now.Add(-d.window))
+			if len(loop.dies) == 0 {
+				delete(d.containers, id)
+			}
+			continue
+		}
+		if loop.runningSince.IsZero() || now.Sub(loop.runningSince) < d.stable {
+			continue
+		}
+		event := loop.event("crash_loop_resolved", now)
+		event.Labels["stable_for"] = d.stable.String()
+		// The last exit stays available to templates as ExitStatus, but
+		// the event is not a failure itself
+		event.ExitCode = ""
+		delete(event.Labels, "exitCode")
+		resolved = append(resolved, event)
+		delete(d.containers, id)
+	}
+	return resolved
+}
+
+// prune drops the deaths before cutoff
+func (l *crashLoop) prune(cutoff time.Time) {
+	i := 0
+	for i < len(l.dies) && !l.dies[i].After(cutoff) {
+		i++
+	}
+	l.dies = l.dies[i:]
+}
+
+func (l *crashLoop) event(action string, at time.Time) notification.Event {
+	labels := maps.Clone(l.attributes)
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	delete(labels, "execDuration")
+	labels["restarts"] = strconv.Itoa(l.restarts)
+	return notification.Event{
+		ContainerName: getContainerName(l.attributes),
+		Action:        action,
+		Timestamp:     at.UTC(),
+		Labels:        labels,
+		ExitCode:      FormatExitCode(l.lastExit),
+		ExitStatus:    l.lastExit,
+		Target:        getNotificationTarget(l.attributes),
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"notidock/config"
+	"notidock/notification"
+	"testing"
+	"time"
+)
+
+func lifecycleEvent(id, action string, at time.Time, attributes map[string]string) Event {
+	return Event{
+		Type:     "container",
+		Action:   action,
+		Actor:    Actor{ID: id, Attributes: attributes},
+		Time:     at.Unix(),
+		TimeNano: at.UnixNano(),
+	}
+}
+
+func TestCrashLoopDetector(t *testing.T) {
+	d := newCrashLoopDetector(config.AppConfig{
+		CrashLoopThreshold: 3,
+		CrashLoopWindow:    5 * time.Minute,
+		CrashLoopStable:    2 * time.Minute,
+	})
+	start := time.Date(2024, 12, 14, 17, 0, 0, 0, time.UTC)
+	attributes := map[string]string{"name": "worker", "exitCode": "1"}
+
+	var alerts []*notification.Event
+	var suppressed int
+	at := start
+	for i := 0; i < 6; i++ {
+		for _, action := range []string{"die", "start"} {
+			alert, inLoop := d.Observe(lifecycleEvent("w", action, at, attributes))
+			if alert != nil {
+				alerts = append(alerts, alert)
+			}
+			if inLoop {
+				suppressed++
+			}
+			at = at.Add(10 * time.Second)
+		}
+	}
+
+	if len(alerts) != 1 {
+		t.Fatalf("got %d alerts, want 1", len(alerts))
+	}
+	alert := alerts[0]
+	if alert.Action != "crash_loop" || alert.Labels["restarts"] != "3" || alert.Labels["window"] != "5m0s" || alert.ExitStatus != "1" {
+		t.Errorf("alert = %+v", alert)
+	}
+	// The third die and every event after it
+	if suppressed != 8 {
+		t.Errorf("suppressed %d events, want 8", suppressed)
+	}
+
+	lastStart := at.Add(-10 * time.Second)
+	if resolved := d.Check(lastStart.Add(time.Minute)); len(resolved) != 0 {
+		t.Errorf("resolved before the stable period: %+v", resolved)
+	}
+	resolved := d.Check(lastStart.Add(2 * time.Minute))
+	if len(resolved) != 1 {
+		t.Fatalf("got %d resolved events, want 1", len(resolved))
+	}
+	if r := resolved[0]; r.Action != "crash_loop_resolved" || r.Labels["restarts"] != "6" || r.ExitCode != "" {
+		t.Errorf("resolved = %+v", r)
+	}
+
+	// The next death starts counting from the beginning
+	if alert, inLoop := d.Observe(lifecycleEvent("w", "die", at.Add(time.Hour), attributes)); alert != nil || inLoop {
+		t.Errorf("die after resolution = %v, %v, want it notified on its own", alert, inLoop)
+	}
+}
+
+func TestCrashLoopDetector_SlowRestarts(t *testing.T) {
+	d := newCrashLoopDetector(config.AppConfig{
+		CrashLoopThreshold: 3,
+		CrashLoopWindow:    time.Minute,
+		CrashLoopStable:    time.Minute,
+	})
+	at := time.Date(2024, 12, 14, 17, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		if alert, inLoop := d.Observe(lifecycleEvent("w", "die", at, nil)); alert != nil || inLoop {
+			t.Fatalf("die %d reported as a crash loop", i)
+		}
+		at = at.Add(45 * time.Second)
+	}
+
+	// Forgotten once it has not died within the window
+	d.Check(at.Add(time.Hour))
+	if len(d.containers) != 0 {
+		t.Errorf("detector still tracks %d containers", len(d.containers))
+	}
+}
+
+func TestHandleContainerEvent_CrashLoop(t *testing.T) {
+	cfg := getTestConfig()
+	cfg.CrashLoopThreshold = 2
+	cfg.CrashLoopWindow = time.Minute
+	cfg.CrashLoopStable = time.Minute
+
+	recorder := &recordingNotifier{}
+	manager := notification.NewManager(notification.ManagerOptions{}, recorder)
+	throttler := NewNotificationThrottler(cfg)
+	crashLoops := newCrashLoopDetector(cfg)
+	health := newHealthTracker(cfg, nil)
+
+	at := time.Now()
+	for _, action := range []string{"die", "start", "die", "start", "die"} {
+		event := lifecycleEvent("w", action, at, map[string]string{"name": "worker", "exitCode": "1"})
+		handleContainerEvent(context.Background(), event, cfg, manager, throttler, health, crashLoops, newEventCorrelator(cfg))
+		at = at.Add(time.Second)
+	}
+	manager.Close(context.Background())
+
+	var actions []string
+	for _, e := range recorder.events {
+		actions = append(actions, e.Action)
+	}
+	want := []string{"die", "start", "crash_loop"}
+	if len(actions) != len(want) {
+		t.Fatalf("notified %q, want %q", actions, want)
+	}
+	for i := range want {
+		if actions[i] != want[i] {
+			t.Errorf("notification %d = %q, want %q", i, actions[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"notidock/config"
+	"time"
+)
+
+// flapDetector recognises containers whose health keeps changing between
+// healthy and unhealthy. Like Nagios it uses two thresholds: a container
+// starts flapping when it changed health high times within the window, and
+// only stops once no more than low changes are left in it, so that it does
+// not go in and out of flapping.
+type flapDetector struct {
+	window     time.Duration
+	high       int
+	low        int
+	containers map[string]*flapState
+}
+
+// flapState is the recent health transition history of a container
+type flapState struct {
+	attributes  map[string]string
+	transitions []time.Time
+	flapping    bool
+	// suppressed counts the transitions not notified while flapping
+	suppressed int
+}
+
+// flapEnd describes a container that stopped flapping
+type flapEnd struct {
+	containerID string
+	attributes  map[string]string
+	suppressed  int
+	// status is the health status of the container, set by the health
+	// tracker
+	status string
+}
+
+func newFlapDetector(cfg config.AppConfig) *flapDetector {
+	d := &flapDetector{containers: make(map[string]*flapState)}
+	d.SetLimits(cfg)
+	return d
+}
+
+// SetLimits applies new flapping settings, keeping the history of every
+// container
+func (d *flapDetector) SetLimits(cfg config.AppConfig) {
+	d.window = cfg.HealthFlapWindow
+	d.high = cfg.HealthFlapHigh
+	d.low = cfg.HealthFlapLow
+}
+
+// Observe records a change between healthy and unhealthy. It reports
+// whether the container started flapping with this change, and whether it
+// is flapping, in which case the change should not be notified.
+func (d *flapDetector) Observe(containerID string, attributes map[string]string, at time.Time) (started, flapping bool) {
+	if d.high <= 0 {
+		return false, false
+	}
+
+	state := d.containers[containerID]
+	if state == nil {
+		state = &flapState{}
+		d.containers[containerID] = state
+	}
+	state.attributes = attributes
+	state.transitions = append(state.transitions, at)
+	state.prune(at.Add(-d.window))
+
+	if state.flapping {
+		state.suppressed++
+		return false, true
+	}
+	if len(state.transitions) >= d.high {
+		state.flapping = true
+		return true, true
+	}
+	return false, false
+}
+
+// Check returns the containers that stopped flapping, and forgets the ones
+// without changes within the window
+func (d *flapDetector) Check(now time.Time) []flapEnd {
+	var ended []flapEnd
+	for id, state := range d.containers {
+		state.prune(now.Add(-d.window))
+		if state.flapping && len(state.transitions) <= d.low {
+			ended = append(ended, flapEnd{containerID: id, attributes: state.attributes, suppressed: state.suppressed})
+			state.flapping = false
+			state.suppressed = 0
+		}
+		if !state.flapping && len(state.transitions) == 0 {
+			delete(d.containers, id)
+		}
+	}
+	return ended
+}
+
+// Forget drops the history of a removed container
+func (d *flapDetector) Forget(containerID string) {
+	delete(d.containers, containerID)
+}
+
+// prune drops the transitions before cutoff
+func (s *flapState) prune(cutoff time.Time) {
+	i := 0
+	for i < len(s.transitions) && !s.transitions[i].After(cutoff) {
+		i++
+	}
+	s.transitions = s.transitions[i:]
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"notidock/config"
+	"notidock/notification"
+	"testing"
+	"time"
+)
+
+func TestFlapDetector(t *testing.T) {
+	d := newFlapDetector(config.AppConfig{
+		HealthFlapWindow: 10 * time.Minute,
+		HealthFlapHigh:   4,
+		HealthFlapLow:    1,
+	})
+	at := time.Date(2024, 12, 14, 17, 0, 0, 0, time.UTC)
+
+	var starts, suppressed int
+	for i := 0; i < 6; i++ {
+		started, flapping := d.Observe("web", nil, at)
+		if started {
+			starts++
+		}
+		if flapping {
+			suppressed++
+		}
+		at = at.Add(time.Minute)
+	}
+	if starts != 1 {
+		t.Errorf("started flapping %d times, want 1", starts)
+	}
+	if suppressed != 3 {
+		t.Errorf("flapping for %d changes, want 3", suppressed)
+	}
+
+	// Still flapping while more than low changes are within the window
+	if ended := d.Check(at.Add(5 * time.Minute)); len(ended) != 0 {
+		t.Errorf("stopped flapping with changes left: %+v", ended)
+	}
+	ended := d.Check(at.Add(9 * time.Minute))
+	if len(ended) != 1 || ended[0].containerID != "web" || ended[0].suppressed != 2 {
+		t.Fatalf("Check() = %+v, want web with 2 suppressed changes", ended)
+	}
+
+	d.Check(at.Add(time.Hour))
+	if len(d.containers) != 0 {
+		t.Errorf("detector still tracks %d containers", len(d.containers))
+	}
+}
+
+func TestFlapDetector_Disabled(t *testing.T) {
+	d := newFlapDetector(config.AppConfig{HealthFlapWindow: time.Minute})
+	at := time.Now()
+	for i := 0; i < 10; i++ {
+		if started, flapping := d.Observe("web", nil, at); started || flapping {
+			t.Fatal("flapping detected with health_flap_high 0")
+		}
+	}
+}
+
+func TestHandleHealthEvents_Flapping(t *testing.T) {
+	cfg := getTestConfig()
+	cfg.MonitorHealth = true
+	cfg.HealthFlapWindow = time.Hour
+	cfg.HealthFlapHigh = 3
+	cfg.HealthFlapLow = 1
+
+	recorder := &recordingNotifier{}
+	manager := notification.NewManager(notification.ManagerOptions{}, recorder)
+	health := newHealthTracker(cfg, nil)
+	crashLoops := newCrashLoopDetector(cfg)
+	throttler := NewNotificationThrottler(cfg)
+
+	at := time.Now()
+	attributes := map[string]string{"name": "web"}
+	for _, action := range []string{
+		"start",
+		"health_status: healthy",
+		"health_status: unhealthy",
+		"health_status: healthy",
+		"health_status: unhealthy",
+		"health_status: healthy",
+		"health_status: unhealthy",
+	} {
+		event := lifecycleEvent("w", action, at, attributes)
+		handleContainerEvent(context.Background(), event, cfg, manager, throttler, health, crashLoops, newEventCorrelator(cfg))
+		at = at.Add(time.Minute)
+	}
+	checkDetectors(context.Background(), at.Add(2*time.Hour), manager, throttler, crashLoops, health)
+	manager.Close(context.Background())
+
+	var got []string
+	for _, e := range recorder.events {
+		got = append(got, e.Action+" "+e.Labels["health_status"])
+	}
+	want := []string{
+		"start ",
+		"health_status healthy",
+		"health_status unhealthy",
+		"health_status healthy",
+		"health_flapping unhealthy",
+		"health_flapping_stopped unhealthy",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("notified %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("notification %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+	if suppressed := recorder.events[5].Labels["suppressed"]; suppressed != "2" {
+		t.Errorf("suppressed = %q, want 2", suppressed)
+	}
+}
@@ -2,7 +2,10 @@ module notidock
 
 go 1.23.4
 
-require github.com/docker/docker v27.4.0+incompatible
+require (
+	github.com/docker/docker v27.4.0+incompatible
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/Microsoft/go-winio v0.4.14 // indirect
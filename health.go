@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"github.com/docker/docker/api/types"
+	"log/slog"
+	"notidock/config"
+	"notidock/notification"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Docker reports health check results as "health_status: <status>" events
+const healthStatusPrefix = "health_status: "
+
+// Health check output longer than this is truncated in notifications
+const maxHealthOutput = 300
+
+// healthInspectTimeout bounds the container inspection done for an
+// unhealthy notification, which delays it
+const healthInspectTimeout = 5 * time.Second
+
+const (
+	healthStarting  = "starting"
+	healthHealthy   = "healthy"
+	healthUnhealthy = "unhealthy"
+)
+
+// parseHealthStatus returns the status of a health_status event
+func parseHealthStatus(action string) (string, bool) {
+	status, ok := strings.CutPrefix(action, healthStatusPrefix)
+	if !ok {
+		return "", false
+	}
+	return strings.TrimSpace(status), true
+}
+
+// healthTracker remembers the last known health status of every container
+// for its whole lifetime, so that only transitions are notified. It is used
+// from the main loop only.
+type healthTracker struct {
+	status map[string]string
+	// inspector looks up the health check results of unhealthy containers,
+	// if set
+	inspector containerInspector
+	flaps     *flapDetector
+	// inspected receives the unhealthy notifications whose health log was
+	// fetched off the main loop. pending holds the later notifications of
+	// the containers being inspected, sent after them to keep the order.
+	inspected chan inspection
+	pending   map[string][]notification.Event
+}
+
+// inspection is an unhealthy notification with the health log of its
+// container
+type inspection struct {
+	containerID string
+	event       notification.Event
+	probes      []notification.HealthProbe
+	streak      int
+	err         error
+}
+
+// containerInspector is implemented by the Docker client
+type containerInspector interface {
+	ContainerInspect(ctx context.Context, containerID string) (types.ContainerJSON, error)
+}
+
+func newHealthTracker(cfg config.AppConfig, inspector containerInspector) *healthTracker {
+	return &healthTracker{
+		status:    make(map[string]string),
+		inspector: inspector,
+		flaps:     newFlapDetector(cfg),
+		inspected: make(chan inspection, 16),
+		pending:   make(map[string][]notification.Event),
+	}
+}
+
+// SetLimits applies new flapping settings
+func (h *healthTracker) SetLimits(cfg config.AppConfig) {
+	h.flaps.SetLimits(cfg)
+}
+
+// Observe updates the tracked state of a container from a lifecycle event.
+// A started container runs its health checks from the beginning again and
+// a destroyed one is forgotten.
+func (h *healthTracker) Observe(containerID, action string) {
+	switch action {
+	case "start":
+		h.status[containerID] = healthStarting
+	case "destroy":
+		delete(h.status, containerID)
+		h.flaps.Forget(containerID)
+	}
+}
+
+// Update records the health status of a container and returns its previous
+// status and whether it changed. Containers that were running before
+// notidock started have no previous status.
+func (h *healthTracker) Update(containerID, status string) (string, bool) {
+	previous := h.status[containerID]
+	h.status[containerID] = status
+	return previous, previous != status
+}
+
+// Flapping records a change of a container's health and reports whether
+// the container started flapping with it, and whether it is flapping. Only
+// changes between healthy and unhealthy count; a restarted container
+// becoming healthy again does not.
+func (h *healthTracker) Flapping(containerID, previous, status string, attributes map[string]string, at time.Time) (started, flapping bool) {
+	if previous != healthHealthy && previous != healthUnhealthy {
+		return false, false
+	}
+	return h.flaps.Observe(containerID, attributes, at)
+}
+
+// StoppedFlapping returns the containers that stopped flapping by now, with
+// their current health status
+func (h *healthTracker) StoppedFlapping(now time.Time) []flapEnd {
+	ended := h.flaps.Check(now)
+	for i := range ended {
+		ended[i].status = h.status[ended[i].containerID]
+	}
+	return ended
+}
+
+// InspectAsync fetches the last n health check results of the container
+// of an unhealthy notification without holding up the main loop. The
+// notification is received from Inspected once they are. It reports false
+// when there is nothing to fetch and the notification can be sent now.
+func (h *healthTracker) InspectAsync(ctx context.Context, containerID string, event notification.Event, n int) bool {
+	if h.inspector == nil || n <= 0 {
+		return false
+	}
+	if h.Hold(containerID, event) {
+		return true
+	}
+	h.pending[containerID] = nil
+	go func() {
+		probes, streak, err := h.Inspect(ctx, containerID, n)
+		select {
+		case h.inspected <- inspection{containerID: containerID, event: event, probes: probes, streak: streak, err: err}:
+		case <-ctx.Done():
+		}
+	}()
+	return true
+}
+
+// Inspected returns the channel InspectAsync delivers its results to
+func (h *healthTracker) Inspected() <-chan inspection {
+	return h.inspected
+}
+
+// Hold keeps the notification of a container until its inspection has
+// finished, and reports whether one is running
+func (h *healthTracker) Hold(containerID string, event notification.Event) bool {
+	held, ok := h.pending[containerID]
+	if ok {
+		h.pending[containerID] = append(held, event)
+	}
+	return ok
+}
+
+// Finish adds the health log to the inspected notification and returns it,
+// followed by the notifications held for the container meanwhile
+func (h *healthTracker) Finish(result inspection) []notification.Event {
+	event := result.event
+	if result.err != nil {
+		slog.Warn("failed to inspect container health",
+			"containerName", event.ContainerName,
+			"containerID", result.containerID,
+			"error", result.err,
+		)
+	}
+	event.HealthLog = result.probes
+	if result.streak > 0 {
+		event.Labels["failing_streak"] = strconv.Itoa(result.streak)
+	}
+	events := append([]notification.Event{event}, h.pending[result.containerID]...)
+	delete(h.pending, result.containerID)
+	return events
+}
+
+// Inspect returns the last n health check results of a container, oldest
+// first, and its current failing streak
+func (h *healthTracker) Inspect(ctx context.Context, containerID string, n int) ([]notification.HealthProbe, int, error) {
+	if h.inspector == nil || n <= 0 {
+		return nil, 0, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, healthInspectTimeout)
+	defer cancel()
+
+	container, err := h.inspector.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if container.ContainerJSONBase == nil || container.State == nil || container.State.Health == nil {
+		return nil, 0, nil
+	}
+
+	results := container.State.Health.Log
+	if len(results) > n {
+		results = results[len(results)-n:]
+	}
+	probes := make([]notification.HealthProbe, 0, len(results))
+	for _, r := range results {
+		if r == nil {
+			continue
+		}
+		probes = append(probes, notification.HealthProbe{
+			Start:    r.Start,
+			End:      r.End,
+			ExitCode: r.ExitCode,
+			Output:   truncateOutput(r.Output, maxHealthOutput),
+		})
+	}
+	return probes, container.State.Health.FailingStreak, nil
+}
+
+// truncateOutput trims s and shortens it to at most n characters
+func truncateOutput(s string, n int) string {
+	s = strings.TrimSpace(s)
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
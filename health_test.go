@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/docker/docker/api/types"
+	"notidock/config"
+	"notidock/notification"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingNotifier struct {
+	mu     sync.Mutex
+	events []notification.Event
+}
+
+func (n *recordingNotifier) Name() string { return "recorder" }
+
+func (n *recordingNotifier) Send(_ context.Context, event notification.Event) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.events = append(n.events, event)
+	return nil
+}
+
+func TestParseHealthStatus(t *testing.T) {
+	tests := []struct {
+		action string
+		want   string
+		ok     bool
+	}{
+		{"health_status: healthy", "healthy", true},
+		{"health_status: unhealthy", "unhealthy", true},
+		{"health_status", "", false},
+		{"start", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := parseHealthStatus(tt.action)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseHealthStatus(%q) = %q, %v, want %q, %v", tt.action, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestHandleHealthEvents(t *testing.T) {
+	cfg := getTestConfig()
+	cfg.MonitorHealth = true
+
+	recorder := &recordingNotifier{}
+	manager := notification.NewManager(notification.ManagerOptions{}, recorder)
+	health := newHealthTracker(cfg, nil)
+	throttler := NewNotificationThrottler(cfg)
+
+	attributes := map[string]string{"name": "web", "image": "web:1.0"}
+	for _, action := range []string{
+		"start",
+		"health_status: healthy",
+		"health_status: healthy",
+		"health_status: unhealthy",
+		"health_status: unhealthy",
+		"health_status: healthy",
+		"die",
+		"start",
+		"health_status: healthy",
+	} {
+		event := Event{Type: "container", Action: action, Actor: Actor{ID: "abc", Attributes: attributes}}
+		handleContainerEvent(context.Background(), event, cfg, manager, throttler, health, newCrashLoopDetector(cfg), newEventCorrelator(cfg))
+	}
+	manager.Close(context.Background())
+
+	var got []string
+	for _, e := range recorder.events {
+		if e.Action != "health_status" {
+			continue
+		}
+		got = append(got, e.Labels["previous_health_status"]+" -> "+e.Labels["health_status"])
+	}
+	want := []string{
+		"starting -> healthy",
+		"healthy -> unhealthy",
+		"unhealthy -> healthy",
+		"starting -> healthy",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("notified transitions = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("transition %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+	if attributes["health_status"] != "" {
+		t.Error("container attributes were modified")
+	}
+}
+
+type fakeInspector struct {
+	health *types.Health
+	err    error
+}
+
+func (f fakeInspector) ContainerInspect(context.Context, string) (types.ContainerJSON, error) {
+	if f.err != nil {
+		return types.ContainerJSON{}, f.err
+	}
+	return types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{State: &types.ContainerState{Health: f.health}},
+	}, nil
+}
+
+// gatedInspector blocks inspections until release is closed
+type gatedInspector struct {
+	fakeInspector
+	release chan struct{}
+}
+
+func (g gatedInspector) ContainerInspect(ctx context.Context, id string) (types.ContainerJSON, error) {
+	<-g.release
+	return g.fakeInspector.ContainerInspect(ctx, id)
+}
+
+// finishInspection does what the main loop does with a finished inspection
+func finishInspection(ctx context.Context, health *healthTracker, manager *notification.Manager, throttler *NotificationThrottler) {
+	for _, event := range health.Finish(<-health.Inspected()) {
+		deliver(ctx, event, manager, throttler)
+	}
+}
+
+func TestHandleHealthEvents_HealthLog(t *testing.T) {
+	cfg := getTestConfig()
+	cfg.MonitorHealth = true
+	cfg.HealthLogEntries = 2
+
+	end := time.Date(2024, 12, 14, 17, 34, 36, 0, time.UTC)
+	inspector := fakeInspector{health: &types.Health{
+		Status:        "unhealthy",
+		FailingStreak: 3,
+		Log: []*types.HealthcheckResult{
+			{End: end.Add(-2 * time.Minute), ExitCode: 0, Output: "ok"},
+			{End: end.Add(-time.Minute), ExitCode: 1, Output: "curl: (7) Failed to connect\n"},
+			{End: end, ExitCode: 1, Output: strings.Repeat("x", 1000)},
+		},
+	}}
+
+	recorder := &recordingNotifier{}
+	manager := notification.NewManager(notification.ManagerOptions{}, recorder)
+	throttler := NewNotificationThrottler(cfg)
+	health := newHealthTracker(cfg, inspector)
+	event := Event{Type: "container", Action: "health_status: unhealthy", Actor: Actor{ID: "abc"}}
+	handleContainerEvent(context.Background(), event, cfg, manager, throttler, health, newCrashLoopDetector(cfg), newEventCorrelator(cfg))
+	finishInspection(context.Background(), health, manager, throttler)
+	manager.Close(context.Background())
+
+	if len(recorder.events) != 1 {
+		t.Fatalf("notified %d events, want 1", len(recorder.events))
+	}
+	got := recorder.events[0]
+	if got.Labels["failing_streak"] != "3" {
+		t.Errorf("failing_streak = %q, want %q", got.Labels["failing_streak"], "3")
+	}
+	if len(got.HealthLog) != 2 {
+		t.Fatalf("health log has %d entries, want 2", len(got.HealthLog))
+	}
+	if p := got.HealthLog[0]; p.ExitCode != 1 || p.Output != "curl: (7) Failed to connect" {
+		t.Errorf("first probe = %+v", p)
+	}
+	if n := len([]rune(got.HealthLog[1].Output)); n != maxHealthOutput {
+		t.Errorf("output has %d characters, want it truncated to %d", n, maxHealthOutput)
+	}
+	if !strings.Contains(got.Body, "exit 1): curl: (7) Failed to connect") {
+		t.Errorf("body does not include the probe output: %q", got.Body)
+	}
+}
+
+func TestHandleHealthEvents_InspectFails(t *testing.T) {
+	cfg := getTestConfig()
+	cfg.MonitorHealth = true
+	cfg.HealthLogEntries = 3
+
+	recorder := &recordingNotifier{}
+	manager := notification.NewManager(notification.ManagerOptions{}, recorder)
+	event := Event{Type: "container", Action: "health_status: unhealthy", Actor: Actor{ID: "abc"}}
+	throttler := NewNotificationThrottler(cfg)
+	health := newHealthTracker(cfg, fakeInspector{err: errors.New("no such container")})
+	handleContainerEvent(context.Background(), event, cfg, manager, throttler, health, newCrashLoopDetector(cfg), newEventCorrelator(cfg))
+	finishInspection(context.Background(), health, manager, throttler)
+	manager.Close(context.Background())
+
+	if len(recorder.events) != 1 || recorder.events[0].HealthLog != nil {
+		t.Errorf("notified %+v, want the event without health log", recorder.events)
+	}
+}
+
+func TestHandleHealthEvents_InspectOffLoop(t *testing.T) {
+	cfg := getTestConfig()
+	cfg.MonitorHealth = true
+	cfg.HealthLogEntries = 1
+
+	inspector := gatedInspector{
+		fakeInspector: fakeInspector{health: &types.Health{
+			Status: "unhealthy",
+			Log:    []*types.HealthcheckResult{{ExitCode: 1, Output: "down"}},
+		}},
+		release: make(chan struct{}),
+	}
+	recorder := &recordingNotifier{}
+	manager := notification.NewManager(notification.ManagerOptions{}, recorder)
+	throttler := NewNotificationThrottler(cfg)
+	health := newHealthTracker(cfg, inspector)
+	ctx := context.Background()
+
+	// Neither event waits for the inspection, and the recovery is held
+	// until the unhealthy notification has been sent
+	for _, action := range []string{"health_status: unhealthy", "health_status: healthy"} {
+		event := Event{Type: "container", Action: action, Actor: Actor{ID: "abc"}}
+		handleContainerEvent(ctx, event, cfg, manager, throttler, health, newCrashLoopDetector(cfg), newEventCorrelator(cfg))
+	}
+	close(inspector.release)
+	finishInspection(ctx, health, manager, throttler)
+	manager.Close(ctx)
+
+	if len(recorder.events) != 2 {
+		t.Fatalf("notified %d events, want 2", len(recorder.events))
+	}
+	if got := recorder.events[0]; got.Labels["health_status"] != "unhealthy" || len(got.HealthLog) != 1 {
+		t.Errorf("first event = %+v, want the unhealthy one with its health log", got)
+	}
+	if got := recorder.events[1]; got.Labels["health_status"] != "healthy" {
+		t.Errorf("second event = %+v, want the recovery", got)
+	}
+}
+
+func TestHandleHealthEvents_Disabled(t *testing.T) {
+	cfg := getTestConfig()
+
+	recorder := &recordingNotifier{}
+	manager := notification.NewManager(notification.ManagerOptions{}, recorder)
+	event := Event{Type: "container", Action: "health_status: unhealthy", Actor: Actor{ID: "abc"}}
+	handleContainerEvent(context.Background(), event, cfg, manager, NewNotificationThrottler(cfg), newHealthTracker(cfg, nil), newCrashLoopDetector(cfg), newEventCorrelator(cfg))
+	manager.Close(context.Background())
+
+	if len(recorder.events) != 0 {
+		t.Errorf("notified %+v with health monitoring disabled", recorder.events)
+	}
+}
+
+func getTestConfig() config.AppConfig {
+	return config.AppConfig{
+		MonitorAllContainers: true,
+		TrackedEvents:        []string{"start", "die"},
+		WindowDuration:       config.DefaultWindowDuration,
+		EventThreshold:       100,
+	}
+}
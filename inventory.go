@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"log/slog"
+	"notidock/config"
+	"notidock/notification"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// containerLister is implemented by the Docker client
+type containerLister interface {
+	ContainerList(ctx context.Context, options container.ListOptions) ([]types.Container, error)
+}
+
+// The container status shown by docker ps, e.g. "Up 2 hours (healthy)" or
+// "Exited (137) 5 minutes ago"
+var (
+	statusHealthPattern   = regexp.MustCompile(`\((?:health: )?(starting|healthy|unhealthy)\)`)
+	statusExitCodePattern = regexp.MustCompile(`^Exited \((-?\d+)\)`)
+)
+
+// inventory describes the monitored containers found at startup
+type inventory struct {
+	Monitored int
+	// Unhealthy and Exited are container names, exited ones with their
+	// exit code
+	Unhealthy []string
+	Exited    []string
+}
+
+// takeInventory lists the existing containers and records the health of the
+// monitored ones, so that containers started before notidock are followed
+// like the ones started after it
+func takeInventory(ctx context.Context, lister containerLister, cfg config.AppConfig, health *healthTracker) (inventory, error) {
+	containers, err := lister.ContainerList(ctx, container.ListOptions{All: true})
+	if err != nil {
+		return inventory{}, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	var inv inventory
+	for _, c := range containers {
+		attributes := containerAttributes(c)
+		if !shouldMonitorContainer(cfg, attributes) {
+			continue
+		}
+		inv.Monitored++
+		name := getContainerName(attributes)
+
+		switch c.State {
+		case "running":
+			status := healthFromStatus(c.Status)
+			if status == "" {
+				continue
+			}
+			health.Update(c.ID, status)
+			if status == healthUnhealthy {
+				inv.Unhealthy = append(inv.Unhealthy, name)
+			}
+		case "exited", "dead":
+			// Dead containers have no exit code in their status
+			switch code := exitCodeFromStatus(c.Status); {
+			case code == "":
+				inv.Exited = append(inv.Exited, name)
+			case code != "0" && shouldTrackExitCode(cfg, code, attributes):
+				inv.Exited = append(inv.Exited, fmt.Sprintf("%s (exit %s)", name, code))
+			}
+		}
+	}
+	return inv, nil
+}
+
+// containerAttributes returns the labels of a listed container with its
+// name, as they appear in the attributes of its events
+func containerAttributes(c types.Container) map[string]string {
+	attributes := make(map[string]string, len(c.Labels)+2)
+	for k, v := range c.Labels {
+		attributes[k] = v
+	}
+	if len(c.Names) > 0 {
+		attributes["name"] = strings.TrimPrefix(c.Names[0], "/")
+	}
+	attributes["image"] = c.Image
+	return attributes
+}
+
+// healthFromStatus returns the health status shown in a container status,
+// or "" for containers without a health check
+func healthFromStatus(status string) string {
+	if m := statusHealthPattern.FindStringSubmatch(status); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// exitCodeFromStatus returns the exit code shown in the status of an exited
+// container, or ""
+func exitCodeFromStatus(status string) string {
+	if m := statusExitCodePattern.FindStringSubmatch(status); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// Event returns the startup inventory notification
+func (inv inventory) Event() notification.Event {
+	labels := map[string]string{
+		"monitored": strconv.Itoa(inv.Monitored),
+	}
+	if len(inv.Unhealthy) > 0 {
+		labels["unhealthy"] = strings.Join(inv.Unhealthy, ", ")
+	}
+	if len(inv.Exited) > 0 {
+		labels["exited"] = strings.Join(inv.Exited, ", ")
+	}
+	return notification.Event{
+		ContainerName: "notidock",
+		Action:        "startup_inventory",
+		Timestamp:     time.Now().UTC(),
+		Labels:        labels,
+	}
+}
+
+// sendInventory takes the startup inventory and, when enabled, notifies it
+func sendInventory(ctx context.Context, lister containerLister, cfg config.AppConfig, health *healthTracker, notificationManager *notification.Manager) {
+	inv, err := takeInventory(ctx, lister, cfg, health)
+	if err != nil {
+		slog.Error("failed to take startup inventory", "error", err)
+		return
+	}
+	slog.Info("startup inventory",
+		"monitored", inv.Monitored,
+		"unhealthy", inv.Unhealthy,
+		"exited", inv.Exited,
+	)
+	if !cfg.StartupInventory {
+		return
+	}
+
+	event := inv.Event()
+	if err := notificationManager.Send(ctx, event); err != nil {
+		logSendError(err, event)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"notidock/notification"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type fakeLister []types.Container
+
+func (f fakeLister) ContainerList(context.Context, container.ListOptions) ([]types.Container, error) {
+	return f, nil
+}
+
+func TestHealthFromStatus(t *testing.T) {
+	tests := map[string]string{
+		"Up 2 hours (healthy)":            "healthy",
+		"Up 5 minutes (unhealthy)":        "unhealthy",
+		"Up 3 seconds (health: starting)": "starting",
+		"Up 2 hours":                      "",
+		"Exited (1) 5 minutes ago":        "",
+	}
+	for status, want := range tests {
+		if got := healthFromStatus(status); got != want {
+			t.Errorf("healthFromStatus(%q) = %q, want %q", status, got, want)
+		}
+	}
+}
+
+func TestExitCodeFromStatus(t *testing.T) {
+	tests := map[string]string{
+		"Exited (137) 5 minutes ago": "137",
+		"Exited (0) 2 days ago":      "0",
+		"Up 2 hours":                 "",
+		"Created":                    "",
+		"Dead":                       "",
+	}
+	for status, want := range tests {
+		if got := exitCodeFromStatus(status); got != want {
+			t.Errorf("exitCodeFromStatus(%q) = %q, want %q", status, got, want)
+		}
+	}
+}
+
+func TestTakeInventory(t *testing.T) {
+	cfg := getTestConfig()
+	cfg.MonitorHealth = true
+	lister := fakeLister{
+		{ID: "a", Names: []string{"/web"}, State: "running", Status: "Up 2 hours (healthy)"},
+		{ID: "b", Names: []string{"/api"}, State: "running", Status: "Up 5 minutes (unhealthy)"},
+		{ID: "c", Names: []string{"/worker"}, State: "exited", Status: "Exited (1) 5 minutes ago"},
+		{ID: "d", Names: []string{"/job"}, State: "exited", Status: "Exited (0) 2 days ago"},
+		{ID: "f", Names: []string{"/broken"}, State: "dead", Status: "Dead"},
+		{ID: "g", Names: []string{"/pending"}, State: "created", Status: "Created"},
+		{ID: "e", Names: []string{"/ignored"}, State: "running", Status: "Up 1 hour (unhealthy)",
+			Labels: map[string]string{LabelExclude: ""}},
+	}
+	health := newHealthTracker(cfg, nil)
+
+	inv, err := takeInventory(context.Background(), lister, cfg, health)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := inventory{Monitored: 6, Unhealthy: []string{"api"}, Exited: []string{"worker (exit 1)", "broken"}}
+	if !reflect.DeepEqual(inv, want) {
+		t.Errorf("takeInventory() = %+v, want %+v", inv, want)
+	}
+
+	// The seeded state means only real transitions are notified
+	recorder := &recordingNotifier{}
+	manager := notification.NewManager(notification.ManagerOptions{}, recorder)
+	for _, action := range []string{"health_status: healthy", "health_status: healthy"} {
+		event := Event{Type: "container", Action: action, Actor: Actor{ID: "b", Attributes: map[string]string{"name": "api"}}}
+		handleContainerEvent(context.Background(), event, cfg, manager, NewNotificationThrottler(cfg), health, newCrashLoopDetector(cfg), newEventCorrelator(cfg))
+	}
+	event := Event{Type: "container", Action: "health_status: healthy", Actor: Actor{ID: "a", Attributes: map[string]string{"name": "web"}}}
+	handleContainerEvent(context.Background(), event, cfg, manager, NewNotificationThrottler(cfg), health, newCrashLoopDetector(cfg), newEventCorrelator(cfg))
+	manager.Close(context.Background())
+
+	if len(recorder.events) != 1 || recorder.events[0].Labels["previous_health_status"] != "unhealthy" {
+		t.Errorf("notified %+v, want only the recovery of api", recorder.events)
+	}
+}
+
+func TestInventory_Event(t *testing.T) {
+	recorder := &recordingNotifier{}
+	manager := notification.NewManager(notification.ManagerOptions{}, recorder)
+	inv := inventory{Monitored: 4, Unhealthy: []string{"api"}, Exited: []string{"worker (exit 1)"}}
+	manager.Send(context.Background(), inv.Event())
+	manager.Close(context.Background())
+
+	if len(recorder.events) != 1 {
+		t.Fatalf("notified %d events, want 1", len(recorder.events))
+	}
+	got := recorder.events[0]
+	if want := "Notidock started, monitoring 4 containers"; got.Title != want {
+		t.Errorf("title = %q, want %q", got.Title, want)
+	}
+	for _, want := range []string{"Unhealthy: api", "Exited: worker (exit 1)"} {
+		if !strings.Contains(got.Body, want) {
+			t.Errorf("body %q does not mention %q", got.Body, want)
+		}
+	}
+}
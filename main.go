@@ -3,15 +3,18 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/docker/docker/client"
 	"log/slog"
+	"maps"
 	"net/http"
 	"net/url"
 	"notidock/config"
 	"notidock/notification"
 	"os"
 	"os/signal"
+	"slices"
 	"strconv"
 	"strings"
 	"syscall"
@@ -42,10 +45,21 @@ const (
 	LabelName          = LabelPrefix + "name"
 	LabelTrackedEvents = LabelPrefix + "events"
 	LabelExitCodes     = LabelPrefix + "exitcodes"
+	LabelNotify        = LabelPrefix + "notify"
+	LabelSlackChannel  = LabelPrefix + "slack.channel"
+	LabelWebhookURL    = LabelPrefix + "webhook.url"
 )
 
 func main() {
-	cfg := config.GetConfig()
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:]))
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "invalid configuration:", err)
+		os.Exit(1)
+	}
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -60,11 +74,39 @@ func main() {
 	}
 
 	throttler := NewNotificationThrottler(cfg)
-	if err != nil {
+	go throttler.periodicCleanup(ctx)
+
+	envNotifiers := setupEnvNotifiers()
+	notificationManager := setupNotificationManager(cfg, envNotifiers)
+	defer func() {
+		drainCtx, drainCancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer drainCancel()
+		if err := notificationManager.Close(drainCtx); err != nil {
+			slog.Warn("pending notifications were not delivered before shutdown", "error", err)
+		}
+	}()
+
+	if err := notificationManager.SetRoutes(buildRoutes(cfg.Routes)); err != nil {
+		panic(err)
+	}
+	if err := setupMessages(notificationManager, cfg); err != nil {
 		panic(err)
 	}
 
-	notificationManager := setupNotificationManager()
+	if cfg.StateDir != "" {
+		spool, err := setupSpool(cfg)
+		if err != nil {
+			panic(err)
+		}
+		notificationManager.SetSpool(spool)
+		go notificationManager.RedeliverDeadLetters(ctx, cfg.DeadLetterInterval)
+	}
+
+	results := newDeliveryResults()
+	notificationManager.OnResult(results.handle)
+	if cfg.StatusAddr != "" {
+		startStatusServer(ctx, cfg.StatusAddr, notificationManager, results)
+	}
 
 	req, err := createEventRequest(ctx)
 	if err != nil {
@@ -79,11 +121,43 @@ func main() {
 
 	decoder := json.NewDecoder(resp.Body)
 	eventChan := processEvents(ctx, decoder)
+	// Taken after subscribing to events, so no health change is missed
+	health := newHealthTracker(cfg, cli)
+	crashLoops := newCrashLoopDetector(cfg)
+	correlator := newEventCorrelator(cfg)
+	// Held events are sent before the notification manager drains
+	defer func() {
+		for _, event := range correlator.FlushAll() {
+			deliver(ctx, event, notificationManager, throttler)
+		}
+	}()
+	var states *stateStore
+	if cfg.StateDir != "" && cfg.Reconcile {
+		states = reconcileOnStartup(ctx, cli, cfg, notificationManager, throttler, health, crashLoops, correlator)
+	}
+	if states != nil {
+		// Saved on shutdown, so the next start knows nothing was missed
+		defer func() {
+			if err := states.Save(); err != nil {
+				slog.Warn("failed to save container state", "error", err)
+			}
+		}()
+	}
+	sendInventory(ctx, cli, cfg, health, notificationManager)
 
 	// Setup signal handling
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
+	// SIGHUP and configuration file changes reload the configuration
+	reloader := newConfigReloader(cfg, notificationManager, throttler, crashLoops, health, correlator, envNotifiers)
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+	reloadChan := make(chan struct{}, 1)
+	if cfg.ConfigFile != "" && cfg.ConfigWatchInterval > 0 {
+		go watchConfigFile(ctx, cfg.ConfigFile, cfg.ConfigWatchInterval, reloadChan)
+	}
+
 	// Log configuration
 	cfg.Log()
 	if len(notificationManager.Notifiers()) == 0 {
@@ -92,6 +166,14 @@ func main() {
 		slog.Info("notification settings", "notifiers_count", len(notificationManager.Notifiers()))
 	}
 
+	// Crash loops, flapping and throttling end without an event
+	checkTicker := time.NewTicker(detectorCheckInterval)
+	defer checkTicker.Stop()
+	correlationTicker := time.NewTicker(correlationCheckInterval)
+	defer correlationTicker.Stop()
+	stateTicker := time.NewTicker(stateFlushInterval)
+	defer stateTicker.Stop()
+
 	slog.Info("---")
 	slog.Info("notidock started, listening for container events...")
 
@@ -100,18 +182,45 @@ func main() {
 		case <-sigChan:
 			slog.Info("shutting down...")
 			return
+		case <-hupChan:
+			reloadConfig(reloader)
+		case <-reloadChan:
+			reloadConfig(reloader)
+		case now := <-checkTicker.C:
+			checkDetectors(ctx, now, notificationManager, throttler, crashLoops, health)
+		case now := <-correlationTicker.C:
+			for _, event := range correlator.Flush(now) {
+				deliver(ctx, event, notificationManager, throttler)
+			}
+		case <-stateTicker.C:
+			if states != nil {
+				states.Flush()
+			}
+		case result := <-health.Inspected():
+			for _, event := range health.Finish(result) {
+				deliver(ctx, event, notificationManager, throttler)
+			}
 		case event, ok := <-eventChan:
 			if !ok {
 				slog.Info("event stream closed")
 				return
 			}
 			if event.Type == "container" {
-				handleContainerEvent(ctx, event, cfg, notificationManager, throttler, cli)
+				handleContainerEvent(ctx, event, reloader.config(), notificationManager, throttler, health, crashLoops, correlator)
+				if states != nil {
+					states.Observe(event)
+				}
 			}
 		}
 	}
 }
 
+func reloadConfig(reloader *configReloader) {
+	if err := reloader.reload(); err != nil {
+		slog.Error("configuration reload failed, keeping the current configuration", "error", err)
+	}
+}
+
 func parseEvents(events string) []string {
 	if events == "" {
 		return []string{"create", "start", "die", "stop", "kill"}
@@ -177,6 +286,24 @@ func shouldMonitorContainer(cfg config.AppConfig, labels map[string]string) bool
 	return included
 }
 
+// getNotificationTarget reads the labels a container uses to choose where
+// its notifications go. It returns nil when none are set.
+func getNotificationTarget(labels map[string]string) *notification.Target {
+	target := notification.Target{
+		SlackChannel: labels[LabelSlackChannel],
+		WebhookURL:   labels[LabelWebhookURL],
+	}
+	for _, name := range strings.Split(labels[LabelNotify], ",") {
+		if trimmed := strings.TrimSpace(name); trimmed != "" {
+			target.Notifiers = append(target.Notifiers, trimmed)
+		}
+	}
+	if len(target.Notifiers) == 0 && target.SlackChannel == "" && target.WebhookURL == "" {
+		return nil
+	}
+	return &target
+}
+
 func getContainerName(labels map[string]string) string {
 	if customName, exists := labels[LabelName]; exists {
 		return customName
@@ -214,14 +341,195 @@ func setupDockerClient(socketPath string) (*client.Client, error) {
 	)
 }
 
-func setupNotificationManager() *notification.Manager {
+func setupNotificationManager(cfg config.AppConfig, envNotifiers []notification.Notifier) *notification.Manager {
 	var notifiers []notification.Notifier
-	if slackNotifier, err := notification.NewSlackNotifier(); err != nil {
-		slog.Error("failed to initialize slack notifier", "error", err)
-	} else {
-		notifiers = append(notifiers, slackNotifier)
+	for _, nc := range cfg.Notifiers {
+		n, err := buildNotifier(nc)
+		if err != nil {
+			slog.Error("failed to initialize notifier", "notifier", nc.Name, "error", err)
+			continue
+		}
+		notifiers = append(notifiers, n)
+	}
+
+	// Notifiers configured through environment variables replace file
+	// notifiers of the same name
+	for _, n := range envNotifiers {
+		i := slices.IndexFunc(notifiers, func(existing notification.Notifier) bool {
+			return existing.Name() == n.Name()
+		})
+		if i >= 0 {
+			notifiers[i] = n
+		} else {
+			notifiers = append(notifiers, n)
+		}
 	}
-	return notification.NewManager(notifiers...)
+
+	for i, n := range notifiers {
+		notifiers[i] = notification.WithRetry(n, retryPolicy(cfg))
+	}
+
+	opts := notification.ManagerOptions{
+		Queue: notification.QueueOptions{
+			Size:     cfg.QueueSize,
+			Workers:  cfg.QueueWorkers,
+			Overflow: notification.OverflowPolicy(cfg.QueueOverflow),
+		},
+		CircuitBreaker: notification.BreakerOptions{
+			FailureThreshold: cfg.CircuitFailureThreshold,
+			Cooldown:         cfg.CircuitCooldown,
+		},
+	}
+	return notification.NewManager(opts, notifiers...)
+}
+
+// setupEnvNotifiers creates the notifiers configured through environment
+// variables, logging the ones that fail to initialize
+func setupEnvNotifiers() []notification.Notifier {
+	notifiers, err := loadEnvNotifiers()
+	if err != nil {
+		slog.Error("failed to initialize notifiers", "error", err)
+	}
+	return notifiers
+}
+
+// loadEnvNotifiers creates the notifiers configured through environment
+// variables. Notifiers that are not configured are skipped.
+func loadEnvNotifiers() ([]notification.Notifier, error) {
+	var notifiers []notification.Notifier
+	var errs []error
+	add := func(name string, n notification.Notifier, err error) {
+		switch {
+		case err == nil:
+			notifiers = append(notifiers, n)
+		case !errors.Is(err, notification.ErrNotConfigured):
+			errs = append(errs, fmt.Errorf("%s notifier: %w", name, err))
+		}
+	}
+
+	slackNotifier, err := notification.NewSlackNotifier()
+	add("slack", slackNotifier, err)
+	natsNotifier, err := notification.NewNATSNotifier()
+	add("nats", natsNotifier, err)
+	redisNotifier, err := notification.NewRedisNotifier()
+	add("redis", redisNotifier, err)
+	webhookNotifier, err := notification.NewWebhookNotifier()
+	add("webhook", webhookNotifier, err)
+	pushoverNotifier, err := notification.NewPushoverNotifier()
+	add("pushover", pushoverNotifier, err)
+	return notifiers, errors.Join(errs...)
+}
+
+// setupMessages sets the templates and time format notifications are
+// rendered with
+func setupMessages(m *notification.Manager, cfg config.AppConfig) error {
+	templates, err := notification.NewTemplates(cfg.Templates)
+	if err != nil {
+		return err
+	}
+	m.SetTemplates(templates)
+	m.SetTimeFormat(timeFormat(cfg))
+	return nil
+}
+
+// timeFormat returns how notification timestamps are shown
+func timeFormat(cfg config.AppConfig) notification.TimeFormat {
+	loc := time.Local
+	if cfg.TimeZone != "" {
+		var err error
+		if loc, err = time.LoadLocation(cfg.TimeZone); err != nil {
+			slog.Warn("unknown time zone, using the local time zone", "time_zone", cfg.TimeZone)
+			loc = time.Local
+		}
+	}
+	return notification.TimeFormat{
+		Location: loc,
+		Layout:   cfg.TimeFormat,
+		Relative: cfg.RelativeTime,
+	}
+}
+
+func retryPolicy(cfg config.AppConfig) notification.RetryPolicy {
+	return notification.RetryPolicy{
+		MaxAttempts:    cfg.RetryMaxAttempts,
+		InitialBackoff: cfg.RetryInitialBackoff,
+		MaxBackoff:     cfg.RetryMaxBackoff,
+		Timeout:        cfg.RetryTimeout,
+	}
+}
+
+// buildNotifier creates a notifier defined in the configuration file
+func buildNotifier(nc config.NotifierConfig) (notification.Notifier, error) {
+	switch nc.Type() {
+	case "slack":
+		return notification.NewSlackNotifierWithOptions(notification.SlackOptions{
+			Name:            nc.Name,
+			WebhookURL:      nc.Slack.WebhookURL,
+			DateTokens:      nc.Slack.DateTokens,
+			AllowedChannels: nc.Slack.AllowedChannels,
+		})
+	case "nats":
+		return notification.NewNATSNotifierWithOptions(notification.NATSOptions{
+			Name:      nc.Name,
+			URL:       nc.NATS.URL,
+			Subject:   nc.NATS.Subject,
+			Token:     nc.NATS.Token,
+			JetStream: nc.NATS.JetStream,
+		})
+	case "redis":
+		maxLen := notification.DefaultRedisMaxLen
+		if nc.Redis.MaxLen != nil {
+			maxLen = *nc.Redis.MaxLen
+		}
+		return notification.NewRedisNotifierWithOptions(notification.RedisOptions{
+			Name:   nc.Name,
+			URL:    nc.Redis.URL,
+			Stream: nc.Redis.Stream,
+			MaxLen: maxLen,
+		})
+	case "pushover":
+		return notification.NewPushoverNotifierWithOptions(notification.PushoverOptions{
+			Name:   nc.Name,
+			Token:  nc.Pushover.Token,
+			User:   nc.Pushover.User,
+			Device: nc.Pushover.Device,
+			Sounds: nc.Pushover.Sounds,
+			Retry:  nc.Pushover.Retry,
+			Expire: nc.Pushover.Expire,
+		})
+	case "webhook":
+		return notification.NewWebhookNotifierWithOptions(notification.WebhookOptions{
+			Name:         nc.Name,
+			URL:          nc.Webhook.URL,
+			AllowedHosts: nc.Webhook.AllowedHosts,
+		})
+	}
+	return nil, fmt.Errorf("notifier %q has no type", nc.Name)
+}
+
+func buildRoutes(routes []config.RouteConfig) []notification.Route {
+	result := make([]notification.Route, 0, len(routes))
+	for _, r := range routes {
+		severities := make([]notification.Severity, 0, len(r.Severities))
+		for _, s := range r.Severities {
+			severities = append(severities, notification.Severity(s))
+		}
+		result = append(result, notification.Route{
+			Name: r.Name,
+			Match: notification.RouteMatch{
+				Containers:      r.Containers,
+				Images:          r.Images,
+				ComposeProjects: r.ComposeProjects,
+				Labels:          r.Labels,
+				Actions:         r.Actions,
+				ExitCodes:       r.ExitCodes,
+				Severities:      severities,
+			},
+			Notifiers: r.Notifiers,
+			Continue:  r.Continue,
+		})
+	}
+	return result
 }
 
 func createEventRequest(ctx context.Context) (*http.Request, error) {
@@ -256,41 +564,53 @@ func processEvents(ctx context.Context, decoder *json.Decoder) chan Event {
 	return eventChan
 }
 
-func handleContainerEvent(ctx context.Context, event Event, cfg config.AppConfig, notificationManager *notification.Manager, throttler *NotificationThrottler, cli *client.Client) {
-	if !shouldMonitorContainer(cfg, event.Actor.Attributes) {
+func handleContainerEvent(ctx context.Context, event Event, cfg config.AppConfig, notificationManager *notification.Manager, throttler *NotificationThrottler, health *healthTracker, crashLoops *crashLoopDetector, correlator *eventCorrelator) {
+	health.Observe(event.Actor.ID, event.Action)
+	if status, ok := parseHealthStatus(event.Action); ok {
+		handleHealthEvent(ctx, event, status, cfg, notificationManager, throttler, health)
 		return
 	}
-	if !shouldTrackEvent(cfg, event.Action, event.Actor.Attributes) {
+
+	if !shouldMonitorContainer(cfg, event.Actor.Attributes) {
 		return
 	}
 
-	exitCode := event.Actor.Attributes["exitCode"]
-	if exitCode != "" && !shouldTrackExitCode(cfg, exitCode, event.Actor.Attributes) {
+	// Crash loops are detected from every lifecycle event, before the
+	// filters and the throttler can hide them
+	alert, inLoop := crashLoops.Observe(event)
+	if alert != nil {
+		slog.Warn("crash loop detected",
+			"containerName", alert.ContainerName,
+			"restarts", alert.Labels["restarts"],
+			"lastExit", alert.ExitStatus,
+		)
+		if err := notificationManager.Send(ctx, *alert); err != nil {
+			logSendError(err, *alert)
+		}
+	}
+	if inLoop {
 		return
 	}
 
-	containerName := getContainerName(event.Actor.Attributes)
-	imageTag := event.Actor.Attributes["image"]
-	if !throttler.ShouldNotify(containerName, imageTag) {
-		slog.Info("notification throttled",
-			"containerName", containerName,
-			"imageTag", imageTag,
-			"action", event.Action,
-		)
+	if !shouldTrackEvent(cfg, event.Action, event.Actor.Attributes) {
 		return
 	}
 
-	// Handle health monitoring for newly created containers
-	if cfg.MonitorHealth && event.Action == "start" {
-		go monitorContainerHealth(ctx, cli, event.Actor.ID, containerName, cfg, notificationManager)
+	exitCode := event.Actor.Attributes["exitCode"]
+	if exitCode != "" && !shouldTrackExitCode(cfg, exitCode, event.Actor.Attributes) {
+		return
 	}
 
+	containerName := getContainerName(event.Actor.Attributes)
+	target := getNotificationTarget(event.Actor.Attributes)
 	exitCodeFormatted := FormatExitCode(exitCode)
 
 	execDuration := "N/A"
+	var runtime time.Duration
 	if durationStr, exists := event.Actor.Attributes["execDuration"]; exists {
 		if duration, err := strconv.ParseInt(durationStr, 10, 64); err == nil {
 			execDuration = FormatDuration(duration)
+			runtime = time.Duration(duration) * time.Second
 		}
 	}
 
@@ -306,106 +626,153 @@ func handleContainerEvent(ctx context.Context, event Event, cfg config.AppConfig
 	notificationEvent := notification.Event{
 		ContainerName: containerName,
 		Action:        event.Action,
-		Time:          FormatTimestamp(event.Time),
+		Timestamp:     eventTime(event),
 		Labels:        event.Actor.Attributes,
 		ExitCode:      exitCodeFormatted,
 		ExecDuration:  execDuration,
+		Target:        target,
+		ExitStatus:    exitCode,
+		Runtime:       runtime,
 	}
 
-	if err := notificationManager.Send(ctx, notificationEvent); err != nil {
-		slog.Error("failed to send notification", "error", err)
+	for _, ready := range correlator.Add(event.Actor.ID, notificationEvent, time.Now()) {
+		deliver(ctx, ready, notificationManager, throttler)
 	}
 }
 
-func monitorContainerHealth(ctx context.Context, cli *client.Client, containerID, containerName string, cfg config.AppConfig, notificationManager *notification.Manager) {
-	timeoutCtx, cancel := context.WithTimeout(ctx, cfg.HealthCheckTimeout)
-	defer cancel()
-
-	ticker := time.NewTicker(2 * time.Second)
-	defer ticker.Stop()
-
-	var lastReportedStatus string
+// deliver sends the notification of container events unless the container
+// is throttled, along with the notices of its throttling
+func deliver(ctx context.Context, event notification.Event, notificationManager *notification.Manager, throttler *NotificationThrottler) {
+	notices, notify := throttler.Check(event, time.Now())
+	for _, notice := range notices {
+		sendThrottleNotice(ctx, notice, notificationManager)
+	}
+	if !notify {
+		slog.Info("notification throttled",
+			"containerName", event.ContainerName,
+			"imageTag", event.Labels["image"],
+			"action", event.Action,
+		)
+		return
+	}
+	if err := notificationManager.Send(ctx, event); err != nil {
+		logSendError(err, event)
+	}
+}
 
-	for {
-		select {
-		case <-timeoutCtx.Done():
-			slog.Warn("health check timeout reached",
-				"containerName", containerName,
-				"containerID", containerID,
-				"timeout", cfg.HealthCheckTimeout,
-			)
-			return
-		case <-ticker.C:
-			container, err := cli.ContainerInspect(ctx, containerID)
-			if err != nil {
-				slog.Error("failed to inspect container",
-					"error", err,
-					"containerName", containerName,
-					"containerID", containerID,
-				)
-				return
-			}
+// sendThrottleNotice sends the notice that the notifications of a container
+// were paused, or the summary of what was suppressed once they resume
+func sendThrottleNotice(ctx context.Context, notice notification.Event, notificationManager *notification.Manager) {
+	if notice.Action == "throttled" {
+		slog.Warn("notifications paused", "containerName", notice.ContainerName, "pausedFor", notice.Labels["paused_for"])
+	} else {
+		slog.Info("notifications resumed", "containerName", notice.ContainerName, "suppressed", notice.Labels["suppressed"])
+	}
+	if err := notificationManager.Send(ctx, notice); err != nil {
+		logSendError(err, notice)
+	}
+}
 
-			// Check if container has health check configured
-			if container.State.Health == nil {
-				slog.Info("container has no health check configured",
-					"containerName", containerName,
-					"containerID", containerID,
-				)
-				return
-			}
+// handleHealthEvent notifies when a container turns healthy or unhealthy.
+// Docker emits health_status events as the result of the container's own
+// health checks, so containers are watched for their whole lifetime without
+// polling.
+func handleHealthEvent(ctx context.Context, event Event, status string, cfg config.AppConfig, notificationManager *notification.Manager, throttler *NotificationThrottler, health *healthTracker) {
+	// Track every container, so that enabling health monitoring on reload
+	// does not report the current status of all of them
+	previous, changed := health.Update(event.Actor.ID, status)
+	if !changed || status == healthStarting {
+		return
+	}
+	if !cfg.MonitorHealth || !shouldMonitorContainer(cfg, event.Actor.Attributes) {
+		return
+	}
 
-			currentStatus := container.State.Health.Status
-			failingStreak := container.State.Health.FailingStreak
-
-			// Only send notifications when status changes or failing streak exceeds threshold
-			if currentStatus != lastReportedStatus ||
-				(failingStreak >= cfg.MaxFailingStreak && currentStatus != "healthy") {
-
-				healthEvent := notification.Event{
-					ContainerName: containerName,
-					Action:        "health_status",
-					Time:          time.Now().Format(time.RFC3339),
-					Labels: map[string]string{
-						"health_status":  currentStatus,
-						"failing_streak": strconv.Itoa(failingStreak),
-					},
-				}
+	containerName := getContainerName(event.Actor.Attributes)
+	started, flapping := health.Flapping(event.Actor.ID, previous, status, event.Actor.Attributes, eventTime(event))
+	if started {
+		slog.Warn("container health started flapping", "containerName", containerName, "containerID", event.Actor.ID)
+		flapEvent := healthFlappingEvent("health_flapping", event.Actor.Attributes, status, eventTime(event))
+		if err := notificationManager.Send(ctx, flapEvent); err != nil {
+			logSendError(err, flapEvent)
+		}
+	}
+	if flapping {
+		return
+	}
 
-				if err := notificationManager.Send(ctx, healthEvent); err != nil {
-					slog.Error("failed to send health notification",
-						"error", err,
-						"containerName", containerName,
-					)
-				}
+	labels := maps.Clone(event.Actor.Attributes)
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	labels["health_status"] = status
+	if previous != "" {
+		labels["previous_health_status"] = previous
+	}
 
-				slog.Info("container health status update",
-					"containerName", containerName,
-					"containerID", containerID,
-					"status", currentStatus,
-					"failingStreak", failingStreak,
-				)
+	slog.Info("container health status changed",
+		"containerName", containerName,
+		"containerID", event.Actor.ID,
+		"status", status,
+		"previousStatus", previous,
+	)
 
-				lastReportedStatus = currentStatus
-			}
+	healthEvent := notification.Event{
+		ContainerName: containerName,
+		Action:        "health_status",
+		Timestamp:     eventTime(event),
+		Labels:        labels,
+		Target:        getNotificationTarget(event.Actor.Attributes),
+	}
+	// Include the output of the failing checks, so the cause is visible
+	// without access to the host. It is fetched off the main loop.
+	if status == healthUnhealthy && health.InspectAsync(ctx, event.Actor.ID, healthEvent, cfg.HealthLogEntries) {
+		return
+	}
+	if health.Hold(event.Actor.ID, healthEvent) {
+		return
+	}
+	deliver(ctx, healthEvent, notificationManager, throttler)
+}
 
-			// Stop monitoring if container is healthy
-			if currentStatus == "healthy" {
-				return
-			}
+// healthFlappingEvent returns the notice of a container starting or
+// stopping to flap, with its current health status
+func healthFlappingEvent(action string, attributes map[string]string, status string, at time.Time) notification.Event {
+	labels := maps.Clone(attributes)
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	labels["health_status"] = status
+	return notification.Event{
+		ContainerName: getContainerName(attributes),
+		Action:        action,
+		Timestamp:     at.UTC(),
+		Labels:        labels,
+		Target:        getNotificationTarget(attributes),
+	}
+}
 
-			// Stop monitoring if failing streak exceeds maximum
-			if failingStreak >= cfg.MaxFailingStreak {
-				slog.Warn("stopping health monitoring due to excessive failures",
-					"containerName", containerName,
-					"containerID", containerID,
-					"failingStreak", failingStreak,
-					"maxAllowed", cfg.MaxFailingStreak,
-				)
-				return
-			}
+// checkDetectors sends the notices of crash loops that were resolved, of
+// containers that stopped flapping and of throttled containers whose
+// notifications resumed
+func checkDetectors(ctx context.Context, now time.Time, notificationManager *notification.Manager, throttler *NotificationThrottler, crashLoops *crashLoopDetector, health *healthTracker) {
+	for _, resolved := range crashLoops.Check(now) {
+		slog.Info("crash loop resolved", "containerName", resolved.ContainerName, "restarts", resolved.Labels["restarts"])
+		if err := notificationManager.Send(ctx, resolved); err != nil {
+			logSendError(err, resolved)
 		}
 	}
+	for _, end := range health.StoppedFlapping(now) {
+		slog.Info("container health stopped flapping", "containerName", getContainerName(end.attributes), "status", end.status)
+		event := healthFlappingEvent("health_flapping_stopped", end.attributes, end.status, now)
+		event.Labels["suppressed"] = strconv.Itoa(end.suppressed)
+		if err := notificationManager.Send(ctx, event); err != nil {
+			logSendError(err, event)
+		}
+	}
+	for _, summary := range throttler.Resume(now) {
+		sendThrottleNotice(ctx, summary, notificationManager)
+	}
 }
 
 func checkDockerConnectivity(ctx context.Context, cli *client.Client) error {
@@ -423,3 +790,24 @@ func checkDockerConnectivity(ctx context.Context, cli *client.Client) error {
 
 	return nil
 }
+
+// logSendError logs the notifiers an event could not be queued for
+func logSendError(err error, event notification.Event) {
+	var sendErr *notification.SendError
+	if !errors.As(err, &sendErr) {
+		slog.Error("failed to send notification",
+			"error", err,
+			"containerName", event.ContainerName,
+			"action", event.Action,
+		)
+		return
+	}
+	for _, f := range sendErr.Failures {
+		slog.Error("failed to queue notification",
+			"notifier", f.Notifier,
+			"error", f.Err,
+			"containerName", event.ContainerName,
+			"action", event.Action,
+		)
+	}
+}
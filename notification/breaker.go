@@ -0,0 +1,133 @@
+package notification
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Default circuit breaker settings
+const (
+	DefaultBreakerFailureThreshold = 5
+	DefaultBreakerCooldown         = time.Minute
+)
+
+// ErrCircuitOpen is reported for events that were not sent because the
+// notifier's circuit breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// CircuitState is the state of a notifier's circuit breaker
+type CircuitState int
+
+const (
+	// CircuitClosed lets every delivery through
+	CircuitClosed CircuitState = iota
+	// CircuitHalfOpen lets a single probe delivery through after the cooldown
+	CircuitHalfOpen
+	// CircuitOpen rejects deliveries until the cooldown has passed
+	CircuitOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitHalfOpen:
+		return "half-open"
+	case CircuitOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+// BreakerOptions configures the per-notifier circuit breakers. A zero
+// FailureThreshold disables them.
+type BreakerOptions struct {
+	FailureThreshold int
+	Cooldown         time.Duration
+}
+
+// circuitBreaker stops deliveries to a notifier after repeated failures
+// and periodically lets a single probe through to detect recovery
+type circuitBreaker struct {
+	mu       sync.Mutex
+	opts     BreakerOptions
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+	lastErr  error
+	now      func() time.Time
+}
+
+func newCircuitBreaker(opts BreakerOptions) *circuitBreaker {
+	if opts.Cooldown <= 0 {
+		opts.Cooldown = DefaultBreakerCooldown
+	}
+	return &circuitBreaker{
+		opts: opts,
+		now:  time.Now,
+	}
+}
+
+// allow reports whether a delivery may be attempted, and whether it is
+// the probe that moved an open circuit to half-open
+func (b *circuitBreaker) allow() (allowed, probing bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if b.now().Sub(b.openedAt) < b.opts.Cooldown {
+			return false, false
+		}
+		b.state = CircuitHalfOpen
+		b.probing = true
+		return true, true
+	case CircuitHalfOpen:
+		if b.probing {
+			return false, false
+		}
+		b.probing = true
+		return true, false
+	default:
+		return true, false
+	}
+}
+
+// success records a successful delivery and returns the previous state
+func (b *circuitBreaker) success() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	previous := b.state
+	b.state = CircuitClosed
+	b.failures = 0
+	b.probing = false
+	b.lastErr = nil
+	return previous
+}
+
+// failure records a failed delivery and returns the previous and new state
+func (b *circuitBreaker) failure(err error) (previous, current CircuitState) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	previous = b.state
+	b.failures++
+	b.probing = false
+	b.lastErr = err
+
+	if b.state == CircuitHalfOpen || b.failures >= b.opts.FailureThreshold {
+		b.state = CircuitOpen
+		b.openedAt = b.now()
+	}
+	return previous, b.state
+}
+
+func (b *circuitBreaker) snapshot() (CircuitState, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state, b.lastErr
+}
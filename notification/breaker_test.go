@@ -0,0 +1,111 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	breaker := newCircuitBreaker(BreakerOptions{FailureThreshold: 3, Cooldown: time.Minute})
+	breaker.now = func() time.Time { return now }
+	failure := errors.New("webhook revoked")
+
+	for i := 0; i < 2; i++ {
+		if allowed, _ := breaker.allow(); !allowed {
+			t.Fatalf("delivery %d should be allowed", i+1)
+		}
+		if _, current := breaker.failure(failure); current != CircuitClosed {
+			t.Fatalf("circuit %s after %d failures, want closed", current, i+1)
+		}
+	}
+
+	// A success resets the consecutive failure count
+	breaker.success()
+	for i := 0; i < 3; i++ {
+		breaker.allow()
+		breaker.failure(failure)
+	}
+	if state, err := breaker.snapshot(); state != CircuitOpen || err != failure {
+		t.Fatalf("circuit %s (%v) after 3 consecutive failures, want open", state, err)
+	}
+	if allowed, _ := breaker.allow(); allowed {
+		t.Error("open circuit should reject deliveries")
+	}
+
+	// After the cooldown a single probe is let through
+	now = now.Add(time.Minute)
+	if allowed, probing := breaker.allow(); !allowed || !probing {
+		t.Fatalf("allow() = %v, %v after cooldown, want probe", allowed, probing)
+	}
+	if allowed, _ := breaker.allow(); allowed {
+		t.Error("only one probe should be allowed while half-open")
+	}
+
+	// A failed probe opens the circuit again
+	if previous, current := breaker.failure(failure); previous != CircuitHalfOpen || current != CircuitOpen {
+		t.Errorf("failed probe moved circuit %s -> %s, want half-open -> open", previous, current)
+	}
+
+	now = now.Add(time.Minute)
+	breaker.allow()
+	if previous := breaker.success(); previous != CircuitHalfOpen {
+		t.Errorf("successful probe from %s, want half-open", previous)
+	}
+	if state, _ := breaker.snapshot(); state != CircuitClosed {
+		t.Errorf("circuit %s after successful probe, want closed", state)
+	}
+}
+
+func TestManager_CircuitBreaker(t *testing.T) {
+	broken := NewMockNotifier("broken")
+	broken.SetError(errors.New("webhook revoked"))
+	healthy := NewMockNotifier("healthy")
+
+	manager := NewManager(ManagerOptions{
+		CircuitBreaker: BreakerOptions{FailureThreshold: 2, Cooldown: time.Hour},
+	}, broken, healthy)
+	defer manager.Close(context.Background())
+	templates, err := NewTemplates(map[string]string{"notifier_down.title": `Circuit open: {{label "notifier"}}`})
+	if err != nil {
+		t.Fatalf("NewTemplates() error = %v", err)
+	}
+	manager.SetTemplates(templates)
+	manager.SetTimeFormat(TimeFormat{Location: time.UTC, Layout: time.Kitchen})
+
+	for i := 0; i < 4; i++ {
+		manager.Send(context.Background(), Event{ContainerName: "test", Action: "die"})
+	}
+
+	waitFor(t, func() bool { return len(healthy.GetEvents()) == 5 })
+
+	if got := len(broken.GetEvents()); got != 2 {
+		t.Errorf("broken notifier attempted %d times, want 2 before the circuit opened", got)
+	}
+
+	stats := manager.Stats()
+	if stats[0].Circuit != CircuitOpen || stats[0].Rejected != 2 {
+		t.Errorf("unexpected stats for broken notifier: %+v", stats[0])
+	}
+
+	var meta *Event
+	for _, e := range healthy.GetEvents() {
+		if e.Action == "notifier_down" {
+			meta = &e
+		}
+	}
+	if meta == nil {
+		t.Fatal("expected a notifier_down notification through the healthy notifier")
+	}
+	if meta.Labels["notifier"] != "broken" || meta.Labels["error"] != "webhook revoked" {
+		t.Errorf("unexpected meta notification: %+v", meta)
+	}
+	if want := "Circuit open: broken"; meta.Title != want {
+		t.Errorf("meta title = %q, want %q from the templates", meta.Title, want)
+	}
+	if meta.Timestamp.IsZero() || meta.Time != meta.Timestamp.Format(time.Kitchen) {
+		t.Errorf("meta time = %q at %s, want the configured format", meta.Time, meta.Timestamp)
+	}
+}
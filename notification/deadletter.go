@@ -0,0 +1,286 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSpoolMaxLetters bounds the number of dead letters kept on disk
+const DefaultSpoolMaxLetters = 1000
+
+// MaxRedeliveries is how often a dead letter is re-delivered in the
+// background before it is parked, about two hours at the default interval
+const MaxRedeliveries = 24
+
+// DeadLetter is a notification that could not be delivered, kept on disk
+// so it can be re-delivered later
+type DeadLetter struct {
+	ID           string    `json:"id"`
+	Notifier     string    `json:"notifier"`
+	Event        Event     `json:"event"`
+	Error        string    `json:"error"`
+	FailedAt     time.Time `json:"failed_at"`
+	Redeliveries int       `json:"redeliveries"`
+	// Parked letters failed permanently or too often and are only
+	// re-delivered on request
+	Parked bool `json:"parked,omitempty"`
+}
+
+// Spool stores dead letters as one JSON file each in a directory
+type Spool struct {
+	mu         sync.Mutex
+	dir        string
+	maxLetters int
+}
+
+// NewSpool opens the dead-letter spool in dir, creating it if needed
+func NewSpool(dir string, maxLetters int) (*Spool, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create dead-letter directory: %w", err)
+	}
+	if maxLetters <= 0 {
+		maxLetters = DefaultSpoolMaxLetters
+	}
+	return &Spool{dir: dir, maxLetters: maxLetters}, nil
+}
+
+// Add stores a failed notification. When the spool is full the oldest
+// letter is discarded to make room.
+func (s *Spool) Add(notifier string, event Event, deliveryErr error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids, err := s.ids()
+	if err != nil {
+		return err
+	}
+	for len(ids) >= s.maxLetters {
+		slog.Warn("dead-letter spool full, discarding oldest letter", "id", ids[0])
+		if err := s.remove(ids[0]); err != nil {
+			return err
+		}
+		ids = ids[1:]
+	}
+
+	now := time.Now()
+	letter := DeadLetter{
+		ID:       fmt.Sprintf("%d-%s", now.UnixNano(), randomID()[:8]),
+		Notifier: notifier,
+		Event:    event,
+		Error:    deliveryErr.Error(),
+		FailedAt: now,
+	}
+	return s.write(letter)
+}
+
+// List returns all dead letters, oldest first
+func (s *Spool) List() ([]DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.list()
+}
+
+// Purge removes the dead letters of the given notifier, or all of them
+// when notifier is empty, and returns how many were removed
+func (s *Spool) Purge(notifier string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	letters, err := s.list()
+	if err != nil {
+		return 0, err
+	}
+	purged := 0
+	for _, l := range letters {
+		if notifier != "" && l.Notifier != notifier {
+			continue
+		}
+		if err := s.remove(l.ID); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// Replay re-delivers dead letters through the matching notifiers. Letters
+// of a notifier are attempted oldest first, and the rest are left alone as
+// soon as one fails with a transient error, since the notifier is evidently
+// still unhealthy. Letters that fail permanently, or have been re-delivered
+// MaxRedeliveries times, are parked so they do not hold up the others.
+// Parked letters are skipped unless parked is set. Only letters of the
+// named notifier are replayed unless it is empty. Every letter is passed
+// through prepare right before it is sent, so its time is shown as of the
+// redelivery rather than of the first attempt.
+func (s *Spool) Replay(ctx context.Context, notifiers []Notifier, prepare func(Event) Event, notifier string, parked bool) (delivered, failed int, err error) {
+	letters, err := s.List()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	byName := make(map[string]Notifier, len(notifiers))
+	for _, n := range notifiers {
+		byName[n.Name()] = n
+	}
+
+	unhealthy := make(map[string]bool)
+	for _, l := range letters {
+		if ctx.Err() != nil {
+			return delivered, failed, ctx.Err()
+		}
+		if notifier != "" && l.Notifier != notifier {
+			continue
+		}
+		if l.Parked && !parked {
+			continue
+		}
+		n, ok := byName[l.Notifier]
+		if !ok || unhealthy[l.Notifier] {
+			continue
+		}
+
+		if sendErr := n.Send(ctx, prepare(l.Event)); sendErr != nil {
+			failed++
+			l.Error = sendErr.Error()
+			l.Redeliveries++
+			if !IsRetryable(sendErr) || l.Redeliveries >= MaxRedeliveries {
+				if !l.Parked {
+					slog.Warn("parking dead letter",
+						"id", l.ID,
+						"notifier", l.Notifier,
+						"redeliveries", l.Redeliveries,
+						"error", sendErr,
+					)
+				}
+				l.Parked = true
+			} else {
+				unhealthy[l.Notifier] = true
+			}
+			if err := s.update(l); err != nil {
+				return delivered, failed, err
+			}
+			continue
+		}
+
+		delivered++
+		s.mu.Lock()
+		err = s.remove(l.ID)
+		s.mu.Unlock()
+		if err != nil {
+			return delivered, failed, err
+		}
+	}
+	return delivered, failed, nil
+}
+
+// update rewrites a letter unless it was removed in the meantime, such as
+// by Add making room or by a purge, so it is not brought back
+func (s *Spool) update(letter DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := os.Stat(s.path(letter.ID)); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read dead letter: %w", err)
+	}
+	return s.write(letter)
+}
+
+// ids returns the IDs of the stored letters, oldest first, without reading
+// them. IDs start with the time the letter was added, so they sort by name.
+func (s *Spool) ids() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead-letter directory: %w", err)
+	}
+
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		ids = append(ids, strings.TrimSuffix(entry.Name(), ".json"))
+	}
+	return ids, nil
+}
+
+func (s *Spool) list() ([]DeadLetter, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead-letter directory: %w", err)
+	}
+
+	letters := make([]DeadLetter, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.dir, entry.Name()))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue // Removed by a concurrent replay
+			}
+			return nil, fmt.Errorf("failed to read dead letter: %w", err)
+		}
+		var letter DeadLetter
+		if err := json.Unmarshal(data, &letter); err != nil {
+			slog.Warn("skipping unreadable dead letter", "file", entry.Name(), "error", err)
+			continue
+		}
+		letters = append(letters, letter)
+	}
+
+	sort.Slice(letters, func(i, j int) bool {
+		return letters[i].FailedAt.Before(letters[j].FailedAt)
+	})
+	return letters, nil
+}
+
+// write stores the letter atomically by renaming a temporary file
+func (s *Spool) write(letter DeadLetter) error {
+	data, err := json.MarshalIndent(letter, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letter: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write dead letter: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write dead letter: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write dead letter: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path(letter.ID)); err != nil {
+		return fmt.Errorf("failed to write dead letter: %w", err)
+	}
+	return nil
+}
+
+func (s *Spool) remove(id string) error {
+	if err := os.Remove(s.path(id)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove dead letter: %w", err)
+	}
+	return nil
+}
+
+func (s *Spool) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
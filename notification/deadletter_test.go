@@ -0,0 +1,256 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSpool(t *testing.T) {
+	spool, err := NewSpool(t.TempDir(), 3)
+	if err != nil {
+		t.Fatalf("failed to create spool: %v", err)
+	}
+
+	for _, action := range []string{"create", "start", "die", "stop"} {
+		if err := spool.Add("slack", Event{ContainerName: "test", Action: action}, errors.New("timeout")); err != nil {
+			t.Fatalf("failed to add dead letter: %v", err)
+		}
+	}
+	if err := spool.Add("pushover", Event{ContainerName: "test", Action: "oom"}, errors.New("invalid user")); err != nil {
+		t.Fatalf("failed to add dead letter: %v", err)
+	}
+
+	letters, err := spool.List()
+	if err != nil {
+		t.Fatalf("failed to list dead letters: %v", err)
+	}
+	var actions []string
+	for _, l := range letters {
+		actions = append(actions, l.Event.Action)
+	}
+	want := []string{"die", "stop", "oom"}
+	if len(actions) != len(want) {
+		t.Fatalf("actions = %v, want %v (oldest discarded when full)", actions, want)
+	}
+	for i := range want {
+		if actions[i] != want[i] {
+			t.Errorf("actions = %v, want %v", actions, want)
+			break
+		}
+	}
+	if letters[2].Notifier != "pushover" || letters[2].Error != "invalid user" {
+		t.Errorf("unexpected letter: %+v", letters[2])
+	}
+
+	purged, err := spool.Purge("slack")
+	if err != nil || purged != 2 {
+		t.Errorf("Purge(slack) = %d, %v; want 2, nil", purged, err)
+	}
+	if letters, _ := spool.List(); len(letters) != 1 {
+		t.Errorf("expected 1 letter after purge, got %d", len(letters))
+	}
+}
+
+func TestSpool_Replay(t *testing.T) {
+	spool, err := NewSpool(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("failed to create spool: %v", err)
+	}
+	for _, name := range []string{"healthy", "healthy", "broken", "broken", "removed"} {
+		spool.Add(name, Event{ContainerName: "test", Action: "die"}, errors.New("timeout"))
+	}
+
+	healthy := NewMockNotifier("healthy")
+	broken := NewMockNotifier("broken")
+	broken.SetError(errors.New("still down"))
+
+	delivered, failed, err := spool.Replay(context.Background(), []Notifier{healthy, broken}, withMessage, "", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if delivered != 2 || failed != 1 {
+		t.Errorf("Replay() = %d delivered, %d failed; want 2, 1", delivered, failed)
+	}
+	if got := len(broken.GetEvents()); got != 1 {
+		t.Errorf("broken notifier attempted %d times, want 1 (stop after first failure)", got)
+	}
+
+	letters, _ := spool.List()
+	if len(letters) != 3 {
+		t.Fatalf("expected 3 remaining letters, got %d", len(letters))
+	}
+	redelivered := 0
+	for _, l := range letters {
+		if l.Redeliveries > 0 {
+			redelivered++
+			if l.Notifier != "broken" || l.Error != "still down" {
+				t.Errorf("unexpected redelivered letter: %+v", l)
+			}
+		}
+	}
+	if redelivered != 1 {
+		t.Errorf("expected 1 letter with a failed redelivery, got %d", redelivered)
+	}
+}
+
+func TestSpool_ReplayParksPoisonLetters(t *testing.T) {
+	spool, err := NewSpool(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("failed to create spool: %v", err)
+	}
+	spool.Add("webhook", Event{ContainerName: "poison", Action: "die"}, errors.New("bad request"))
+	spool.Add("webhook", Event{ContainerName: "good", Action: "die"}, errors.New("timeout"))
+
+	// The first letter is rejected for good, the second goes through
+	notifier := &poisonNotifier{MockNotifier: NewMockNotifier("webhook")}
+	delivered, failed, err := spool.Replay(context.Background(), []Notifier{notifier}, withMessage, "", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if delivered != 1 || failed != 1 {
+		t.Errorf("Replay() = %d delivered, %d failed; want 1, 1", delivered, failed)
+	}
+
+	letters, _ := spool.List()
+	if len(letters) != 1 || !letters[0].Parked || letters[0].Event.ContainerName != "poison" {
+		t.Fatalf("expected the poison letter to be parked, got %+v", letters)
+	}
+
+	// Parked letters are left to the deadletter command
+	if _, failed, _ := spool.Replay(context.Background(), []Notifier{notifier}, withMessage, "", false); failed != 0 {
+		t.Errorf("background replay attempted a parked letter")
+	}
+	if _, failed, _ := spool.Replay(context.Background(), []Notifier{notifier}, withMessage, "", true); failed != 1 {
+		t.Errorf("replay of parked letters attempted %d, want 1", failed)
+	}
+}
+
+func TestSpool_ReplayParksAfterMaxRedeliveries(t *testing.T) {
+	spool, err := NewSpool(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("failed to create spool: %v", err)
+	}
+	spool.Add("slack", Event{ContainerName: "test", Action: "die"}, errors.New("timeout"))
+
+	broken := NewMockNotifier("slack")
+	broken.SetError(errors.New("still down"))
+	for i := 0; i < MaxRedeliveries+1; i++ {
+		spool.Replay(context.Background(), []Notifier{broken}, withMessage, "", false)
+	}
+
+	if got := len(broken.GetEvents()); got != MaxRedeliveries {
+		t.Errorf("re-delivered %d times, want %d", got, MaxRedeliveries)
+	}
+	letters, _ := spool.List()
+	if len(letters) != 1 || !letters[0].Parked {
+		t.Errorf("expected the letter to be parked, got %+v", letters)
+	}
+}
+
+// evictingNotifier fails while a full spool discards the letter being
+// replayed to make room for a new one
+type evictingNotifier struct {
+	*MockNotifier
+	spool *Spool
+}
+
+func (e *evictingNotifier) Send(ctx context.Context, event Event) error {
+	e.spool.Add("other", Event{ContainerName: "test", Action: "oom"}, errors.New("timeout"))
+	return e.MockNotifier.Send(ctx, event)
+}
+
+func TestSpool_ReplayDoesNotResurrectEvictedLetters(t *testing.T) {
+	spool, err := NewSpool(t.TempDir(), 1)
+	if err != nil {
+		t.Fatalf("failed to create spool: %v", err)
+	}
+	spool.Add("slack", Event{ContainerName: "test", Action: "die"}, errors.New("timeout"))
+
+	broken := &evictingNotifier{MockNotifier: NewMockNotifier("slack"), spool: spool}
+	broken.SetError(errors.New("still down"))
+	if _, _, err := spool.Replay(context.Background(), []Notifier{broken}, withMessage, "", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	letters, _ := spool.List()
+	if len(letters) != 1 || letters[0].Event.Action != "oom" {
+		t.Errorf("expected only the letter that evicted the replayed one, got %+v", letters)
+	}
+}
+
+// poisonNotifier permanently rejects the events of the poison container
+type poisonNotifier struct {
+	*MockNotifier
+}
+
+func (p *poisonNotifier) Send(ctx context.Context, event Event) error {
+	if event.ContainerName == "poison" {
+		return Permanent(errors.New("bad request"))
+	}
+	return p.MockNotifier.Send(ctx, event)
+}
+
+func TestManager_DeadLetters(t *testing.T) {
+	spool, err := NewSpool(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("failed to create spool: %v", err)
+	}
+
+	failing := NewMockNotifier("failing")
+	failing.SetError(errors.New("webhook revoked"))
+	working := NewMockNotifier("working")
+
+	manager := NewManager(ManagerOptions{}, failing, working)
+	manager.SetSpool(spool)
+	manager.Send(context.Background(), Event{ContainerName: "test", Action: "die"})
+	manager.Close(context.Background())
+
+	letters, err := spool.List()
+	if err != nil {
+		t.Fatalf("failed to list dead letters: %v", err)
+	}
+	if len(letters) != 1 || letters[0].Notifier != "failing" || letters[0].Error != "webhook revoked" {
+		t.Errorf("unexpected dead letters: %+v", letters)
+	}
+}
+
+func TestManager_DeadLettersFormattedWhenSent(t *testing.T) {
+	spool, err := NewSpool(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("failed to create spool: %v", err)
+	}
+	failing := NewMockNotifier("slack")
+	failing.SetError(errors.New("webhook revoked"))
+
+	manager := NewManager(ManagerOptions{}, failing)
+	manager.SetSpool(spool)
+	manager.SetTimeFormat(TimeFormat{Location: time.UTC, Relative: true})
+	failedAt := time.Now().Add(-2 * time.Hour)
+	manager.Send(context.Background(), Event{ContainerName: "test", Action: "die", Timestamp: failedAt})
+	manager.Close(context.Background())
+
+	if events := failing.GetEvents(); len(events) != 1 || !strings.HasSuffix(events[0].Time, "(2h ago)") {
+		t.Fatalf("delivery attempt = %+v, want the time formatted when sent", events)
+	}
+	letters, _ := spool.List()
+	if len(letters) != 1 || letters[0].Event.Time != "" || letters[0].Event.Title != "" {
+		t.Fatalf("dead letters = %+v, want the event as sent to the manager", letters)
+	}
+
+	// A letter is shown as of its redelivery
+	spool.Purge("")
+	spool.Add("slack", Event{ContainerName: "test", Action: "die", Timestamp: time.Now().Add(-25 * time.Hour)}, errors.New("timeout"))
+	working := NewMockNotifier("slack")
+	replayer := NewManager(ManagerOptions{}, working)
+	replayer.SetTimeFormat(TimeFormat{Location: time.UTC, Relative: true})
+	defer replayer.Close(context.Background())
+	if delivered, _, err := replayer.ReplayDeadLetters(context.Background(), spool, "", false); err != nil || delivered != 1 {
+		t.Fatalf("ReplayDeadLetters() = %d, %v; want 1 delivered", delivered, err)
+	}
+	if events := working.GetEvents(); len(events) != 1 || !strings.HasSuffix(events[0].Time, "(1d ago)") || events[0].Title == "" {
+		t.Errorf("redelivered %+v, want it rendered with the time as of now", events)
+	}
+}
@@ -0,0 +1,32 @@
+package notification
+
+import (
+	"errors"
+	"net/url"
+	"notidock/config"
+)
+
+// envVars reads notifier settings from environment variables, or from the
+// files named by their _FILE variants. The first error is kept so that a
+// constructor can check once after reading all of its settings.
+type envVars struct {
+	err error
+}
+
+func (e *envVars) get(name string) string {
+	value, err := config.LookupEnv(name)
+	if err != nil && e.err == nil {
+		e.err = err
+	}
+	return value
+}
+
+// redactURLError hides the URL in errors from the HTTP client and URL
+// parser, as webhook URLs carry their secret in the path
+func redactURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = config.RedactURL(urlErr.URL)
+	}
+	return err
+}
@@ -0,0 +1,299 @@
+package notification
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultNATSSubject = "notidock.events"
+	defaultNATSPort    = "4222"
+	natsTimeout        = 5 * time.Second
+)
+
+// NATSNotifier publishes events as JSON to a NATS subject. When JetStream
+// is enabled the publish waits for the stream acknowledgement.
+//
+// A connection is opened per event, which keeps the notifier free of
+// reconnect logic at the cost of a handshake for every message.
+type NATSNotifier struct {
+	name      string
+	addr      string
+	useTLS    bool
+	host      string
+	user      string
+	password  string
+	token     string
+	subject   string
+	jetStream bool
+	timeout   time.Duration
+}
+
+// NATSOptions configures a NATS notifier
+type NATSOptions struct {
+	// Name identifies the notifier in routes and logs, "nats" by default
+	Name      string
+	URL       string
+	Subject   string
+	Token     string
+	JetStream bool
+}
+
+type natsInfo struct {
+	TLSRequired bool `json:"tls_required"`
+}
+
+type natsConnect struct {
+	Verbose   bool   `json:"verbose"`
+	Pedantic  bool   `json:"pedantic"`
+	Name      string `json:"name"`
+	Lang      string `json:"lang"`
+	Version   string `json:"version"`
+	Protocol  int    `json:"protocol"`
+	User      string `json:"user,omitempty"`
+	Pass      string `json:"pass,omitempty"`
+	AuthToken string `json:"auth_token,omitempty"`
+}
+
+type jetStreamAck struct {
+	Stream string `json:"stream"`
+	Seq    uint64 `json:"seq"`
+	Error  *struct {
+		Code        int    `json:"code"`
+		Description string `json:"description"`
+	} `json:"error,omitempty"`
+}
+
+func NewNATSNotifier() (*NATSNotifier, error) {
+	var env envVars
+	opts := NATSOptions{
+		URL:     env.get("NOTIDOCK_NATS_URL"),
+		Subject: env.get("NOTIDOCK_NATS_SUBJECT"),
+		Token:   env.get("NOTIDOCK_NATS_TOKEN"),
+	}
+	jetStream := env.get("NOTIDOCK_NATS_JETSTREAM")
+	if env.err != nil {
+		return nil, env.err
+	}
+	if opts.URL == "" {
+		return nil, ErrNotConfigured
+	}
+	if jetStream != "" {
+		var err error
+		if opts.JetStream, err = strconv.ParseBool(jetStream); err != nil {
+			return nil, fmt.Errorf("invalid NOTIDOCK_NATS_JETSTREAM %q: must be true or false", jetStream)
+		}
+	}
+	return NewNATSNotifierWithOptions(opts)
+}
+
+// NewNATSNotifierWithOptions creates a NATS notifier from explicit settings
+func NewNATSNotifierWithOptions(opts NATSOptions) (*NATSNotifier, error) {
+	parsedURL, err := url.Parse(opts.URL)
+	if err != nil || (parsedURL.Scheme != "nats" && parsedURL.Scheme != "tls") || parsedURL.Hostname() == "" {
+		return nil, errors.New("invalid NATS URL: must be nats://host[:port] or tls://host[:port]")
+	}
+
+	port := parsedURL.Port()
+	if port == "" {
+		port = defaultNATSPort
+	}
+
+	n := &NATSNotifier{
+		name:      opts.Name,
+		addr:      net.JoinHostPort(parsedURL.Hostname(), port),
+		useTLS:    parsedURL.Scheme == "tls",
+		host:      parsedURL.Hostname(),
+		token:     opts.Token,
+		subject:   opts.Subject,
+		jetStream: opts.JetStream,
+		timeout:   natsTimeout,
+	}
+	if n.name == "" {
+		n.name = "nats"
+	}
+	if parsedURL.User != nil {
+		n.user = parsedURL.User.Username()
+		n.password, _ = parsedURL.User.Password()
+	}
+	if n.subject == "" {
+		n.subject = DefaultNATSSubject
+	}
+	if strings.ContainsAny(n.subject, " \t\r\n") {
+		return nil, fmt.Errorf("invalid NATS subject %q: must not contain whitespace", n.subject)
+	}
+
+	return n, nil
+}
+
+func (n *NATSNotifier) Name() string {
+	return n.name
+}
+
+// Send implements the Notifier interface for NATS
+func (n *NATSNotifier) Send(ctx context.Context, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal nats message: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, n.timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", n.addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to nats: %w", err)
+	}
+	defer conn.Close()
+
+	// Unblock any pending read or write once the context is done
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("failed to read nats server info: %w", contextErr(ctx, err))
+	}
+	op, args, _ := strings.Cut(strings.TrimRight(line, "\r\n"), " ")
+	if op != "INFO" {
+		return fmt.Errorf("unexpected nats greeting: %q", line)
+	}
+	var info natsInfo
+	if err := json.Unmarshal([]byte(args), &info); err != nil {
+		return fmt.Errorf("failed to parse nats server info: %w", err)
+	}
+
+	if n.useTLS || info.TLSRequired {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: n.host})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return fmt.Errorf("nats tls handshake failed: %w", err)
+		}
+		conn = tlsConn
+		reader = bufio.NewReader(conn)
+	}
+
+	connect, err := json.Marshal(natsConnect{
+		Name:      "notidock",
+		Lang:      "go",
+		Version:   "1",
+		Protocol:  1,
+		User:      n.user,
+		Pass:      n.password,
+		AuthToken: n.token,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal nats connect: %w", err)
+	}
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "CONNECT %s\r\n", connect)
+
+	inbox := ""
+	if n.jetStream {
+		inbox = "_INBOX." + randomID()
+		fmt.Fprintf(&buf, "SUB %s 1\r\n", inbox)
+		fmt.Fprintf(&buf, "PUB %s %s %d\r\n", n.subject, inbox, len(payload))
+	} else {
+		fmt.Fprintf(&buf, "PUB %s %d\r\n", n.subject, len(payload))
+	}
+	buf.Write(payload)
+	buf.WriteString("\r\nPING\r\n")
+
+	if _, err := conn.Write([]byte(buf.String())); err != nil {
+		return fmt.Errorf("failed to publish nats message: %w", contextErr(ctx, err))
+	}
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("failed to read nats response: %w", contextErr(ctx, err))
+		}
+		line = strings.TrimRight(line, "\r\n")
+		op, args, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(op) {
+		case "PING":
+			if _, err := conn.Write([]byte("PONG\r\n")); err != nil {
+				return fmt.Errorf("failed to answer nats ping: %w", contextErr(ctx, err))
+			}
+		case "PONG":
+			// The server has processed the publish; core NATS is done,
+			// JetStream still has to acknowledge it
+			if !n.jetStream {
+				return nil
+			}
+		case "-ERR":
+			return natsServerError(args)
+		case "MSG":
+			fields := strings.Fields(args)
+			if len(fields) < 3 {
+				return fmt.Errorf("invalid nats message header: %q", line)
+			}
+			size, err := strconv.Atoi(fields[len(fields)-1])
+			if err != nil {
+				return fmt.Errorf("invalid nats message header: %q", line)
+			}
+			body := make([]byte, size+2)
+			if _, err := io.ReadFull(reader, body); err != nil {
+				return fmt.Errorf("failed to read jetstream ack: %w", contextErr(ctx, err))
+			}
+			if fields[0] != inbox {
+				continue
+			}
+			var ack jetStreamAck
+			if err := json.Unmarshal(body[:size], &ack); err != nil {
+				return fmt.Errorf("failed to parse jetstream ack: %w", err)
+			}
+			if ack.Error != nil {
+				return fmt.Errorf("jetstream publish failed with code %d: %s", ack.Error.Code, ack.Error.Description)
+			}
+			return nil
+		}
+	}
+}
+
+// natsTransientErrors are the server errors that may clear up on their own
+var natsTransientErrors = []string{"slow consumer", "stale connection", "maximum connections exceeded"}
+
+// natsServerError builds the error of an -ERR reply. Errors such as an
+// authorization or permissions violation are permanent.
+func natsServerError(message string) error {
+	err := fmt.Errorf("nats server error: %s", message)
+	lower := strings.ToLower(message)
+	for _, transient := range natsTransientErrors {
+		if strings.Contains(lower, transient) {
+			return err
+		}
+	}
+	return Permanent(err)
+}
+
+// contextErr prefers the context error over the I/O error it caused
+func contextErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+func randomID() string {
+	b := make([]byte, 11)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -1,16 +1,62 @@
 package notification
 
-import "context"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrNotConfigured is returned by notifier constructors when the
+// notifier has not been enabled through its environment variables
+var ErrNotConfigured = errors.New("notifier not configured")
 
 // Event represents a container event that can be sent
 // via notifications
 type Event struct {
-	ContainerName string
-	Action        string
-	Time          string // Changed to string to store formatted time
-	Labels        map[string]string
-	ExitCode      string
-	ExecDuration  string
+	ContainerName string            `json:"container_name"`
+	Action        string            `json:"action"`
+	Time          string            `json:"time"` // Timestamp formatted for display, see TimeFormat
+	Timestamp     time.Time         `json:"timestamp"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	ExitCode      string            `json:"exit_code,omitempty"`
+	ExecDuration  string            `json:"exec_duration,omitempty"`
+	Target        *Target           `json:"target,omitempty"`
+
+	// ExitStatus is the bare exit code and Runtime how long the container
+	// ran, for use in templates
+	ExitStatus string        `json:"exit_status,omitempty"`
+	Runtime    time.Duration `json:"runtime,omitempty"`
+
+	// HealthLog holds the latest health check results, oldest first, of a
+	// container that turned unhealthy
+	HealthLog []HealthProbe `json:"health_log,omitempty"`
+
+	// Title, Body and Summary are the message rendered from the templates
+	Title   string `json:"title,omitempty"`
+	Body    string `json:"body,omitempty"`
+	Summary string `json:"summary,omitempty"`
+}
+
+// HealthProbe is the result of one run of a container's health check
+type HealthProbe struct {
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	ExitCode int       `json:"exit_code"`
+	Output   string    `json:"output"`
+}
+
+// Target overrides where an event is delivered, usually set from the
+// labels of the container the event is about
+type Target struct {
+	// Notifiers replaces the notifiers chosen by the routing rules
+	Notifiers    []string `json:"notifiers,omitempty"`
+	SlackChannel string   `json:"slack_channel,omitempty"`
+	WebhookURL   string   `json:"webhook_url,omitempty"`
 }
 
 type Notifier interface {
@@ -18,33 +64,541 @@ type Notifier interface {
 	Name() string
 }
 
-// Manager handles multiple notification methods
+// RecoveryObserver is implemented by notifiers that need to know when a
+// container recovers even if the event is routed elsewhere, such as
+// Pushover cancelling its outstanding emergency alert
+type RecoveryObserver interface {
+	Recovered(ctx context.Context, event Event)
+}
+
+// inheritor is implemented by notifiers that take over state from the
+// notifier with the same name they replace on a reload
+type inheritor interface {
+	inherit(old Notifier)
+}
+
+// unwrap returns the notifier inside wrappers such as RetryNotifier, so
+// the optional interfaces of the notifier itself can be found
+func unwrap(n Notifier) Notifier {
+	for {
+		wrapper, ok := n.(interface{ Unwrap() Notifier })
+		if !ok {
+			return n
+		}
+		n = wrapper.Unwrap()
+	}
+}
+
+// Manager handles multiple notification methods. Every notifier gets its
+// own bounded queue drained by its own workers, so Send never waits on a
+// slow notifier.
 type Manager struct {
-	notifiers []Notifier
+	mu     sync.RWMutex
+	opts   ManagerOptions
+	queues []*deliveryQueue
+	// retired queues belong to replaced or removed notifiers and are
+	// still draining the events queued before. They are removed once
+	// drained.
+	retired []*deliveryQueue
+	routes  []Route
+	ctx     context.Context
+	cancel  context.CancelFunc
+	closed  bool
+	spool   atomic.Pointer[Spool]
+
+	templates  atomic.Pointer[Templates]
+	timeFormat atomic.Pointer[TimeFormat]
+
+	resultHandler atomic.Pointer[func(SendResult)]
+}
+
+// ManagerOptions configures how the manager delivers events
+type ManagerOptions struct {
+	Queue          QueueOptions
+	CircuitBreaker BreakerOptions
 }
 
 // NewManager creates a new notification manager
-func NewManager(notifiers ...Notifier) *Manager {
-	return &Manager{
-		notifiers: notifiers,
+func NewManager(opts ManagerOptions, notifiers ...Notifier) *Manager {
+	if opts.Queue.Size <= 0 {
+		opts.Queue.Size = DefaultQueueSize
+	}
+	if opts.Queue.Workers <= 0 {
+		opts.Queue.Workers = DefaultQueueWorkers
 	}
+	if opts.Queue.Overflow == "" {
+		opts.Queue.Overflow = OverflowDropOldest
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Manager{
+		opts:   opts,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	for _, n := range notifiers {
+		m.AddNotifier(n)
+	}
+	return m
 }
 
-// Send sends the event to all configured notifiers
+// Send queues the event for delivery by the notifiers its target or route
+// selects, or by all notifiers when no route matches. The
+// returned *SendError only covers notifiers the event could not be queued
+// for; delivery outcomes are reported to the OnResult handler.
 func (m *Manager) Send(ctx context.Context, event Event) error {
-	var lastErr error
-	for _, n := range m.notifiers {
-		if err := n.Send(ctx, event); err != nil {
-			lastErr = err
+	// Queues are not sent to under m.mu, so a full queue blocking the send
+	// never holds up Close or a reload
+	m.mu.RLock()
+	if m.closed {
+		m.mu.RUnlock()
+		return errors.New("notification manager is closed")
+	}
+	targets := m.route(event)
+	observers := m.observers(event, targets)
+	m.mu.RUnlock()
+
+	for _, q := range observers {
+		if err := q.enqueue(ctx, queuedEvent{event: event, recovery: true}); err != nil {
+			slog.Warn("failed to queue recovery for notifier",
+				"notifier", q.notifier.Name(),
+				"containerName", event.ContainerName,
+				"error", err,
+			)
+		}
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	pending := newPendingSend(event, len(targets), m.handleResult)
+	var failures []NotifierResult
+	for _, q := range targets {
+		if err := q.enqueue(ctx, queuedEvent{event: event, pending: pending}); err != nil {
+			res := NotifierResult{Notifier: q.notifier.Name(), Err: err}
+			failures = append(failures, res)
+			pending.record(res)
+		}
+	}
+	if len(failures) > 0 {
+		return &SendError{Failures: failures}
+	}
+	return nil
+}
+
+// prepare formats the event's timestamp and renders its message from the
+// templates. It runs right before every delivery attempt rather than when
+// the event is queued, so a relative time such as "(just now)" is still
+// true when a queued event or a dead letter is finally sent.
+func (m *Manager) prepare(event Event) Event {
+	if !event.Timestamp.IsZero() {
+		format := defaultTimeFormat
+		if f := m.timeFormat.Load(); f != nil {
+			format = *f
 		}
+		event.Time = format.Format(event.Timestamp, time.Now())
+	}
+
+	templates := builtinTemplates
+	if t := m.templates.Load(); t != nil {
+		templates = t
+	}
+	event, err := templates.Apply(event)
+	if err != nil {
+		slog.Warn("failed to render notification template, using the default",
+			"containerName", event.ContainerName,
+			"action", event.Action,
+			"error", err,
+		)
+	}
+	return event
+}
+
+// SetRoutes replaces the routing rules. Every notifier a route names must
+// already have been added.
+func (m *Manager) SetRoutes(routes []Route) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.queues))
+	for _, q := range m.queues {
+		names = append(names, q.notifier.Name())
+	}
+	if err := ValidateRoutes(routes, names); err != nil {
+		return err
+	}
+	m.routes = routes
+	return nil
+}
+
+// ValidateRoutes checks the routes' patterns and that every notifier they
+// name is one of notifiers
+func ValidateRoutes(routes []Route, notifiers []string) error {
+	for i, r := range routes {
+		name := r.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+		if err := r.Match.validate(); err != nil {
+			return fmt.Errorf("route %s: %w", name, err)
+		}
+		for _, n := range r.Notifiers {
+			if !slices.Contains(notifiers, n) {
+				return fmt.Errorf("route %s: unknown notifier %q", name, n)
+			}
+		}
+	}
+	return nil
+}
+
+// route returns the queues of the notifiers the event is routed to. The
+// caller must hold m.mu.
+func (m *Manager) route(event Event) []*deliveryQueue {
+	if event.Target != nil && len(event.Target.Notifiers) > 0 {
+		if queues := m.queuesNamed(event.Target.Notifiers); len(queues) > 0 {
+			return queues
+		}
+		slog.Warn("event targets no configured notifier, using routes instead",
+			"containerName", event.ContainerName,
+			"notifiers", event.Target.Notifiers,
+		)
+	}
+	if len(m.routes) == 0 {
+		return slices.Clone(m.queues)
+	}
+
+	var names []string
+	matched := false
+	for _, r := range m.routes {
+		if !r.Match.matches(event) {
+			continue
+		}
+		matched = true
+		names = append(names, r.Notifiers...)
+		if !r.Continue {
+			break
+		}
+	}
+	if !matched {
+		return slices.Clone(m.queues)
+	}
+	return m.queuesNamed(names)
+}
+
+// observers returns the queues of the notifiers that observe recoveries
+// but are not among the targets of the recovery event. The caller must
+// hold m.mu.
+func (m *Manager) observers(event Event, targets []*deliveryQueue) []*deliveryQueue {
+	if !isRecovery(event) {
+		return nil
+	}
+	var queues []*deliveryQueue
+	for _, q := range m.queues {
+		if _, ok := unwrap(q.notifier).(RecoveryObserver); ok && !slices.Contains(targets, q) {
+			queues = append(queues, q)
+		}
+	}
+	return queues
+}
+
+func (m *Manager) queuesNamed(names []string) []*deliveryQueue {
+	var queues []*deliveryQueue
+	for _, q := range m.queues {
+		if slices.Contains(names, q.notifier.Name()) {
+			queues = append(queues, q)
+		}
+	}
+	return queues
+}
+
+// SetTemplates sets the templates messages are rendered from
+func (m *Manager) SetTemplates(t *Templates) {
+	m.templates.Store(t)
+}
+
+// SetTimeFormat sets how the timestamps of events are shown
+func (m *Manager) SetTimeFormat(f TimeFormat) {
+	m.timeFormat.Store(&f)
+}
+
+// OnResult sets the handler that receives the outcome of every event once
+// all notifiers have finished with it. Without a handler, failures are
+// logged by the manager.
+func (m *Manager) OnResult(handler func(SendResult)) {
+	m.resultHandler.Store(&handler)
+}
+
+func (m *Manager) handleResult(result SendResult) {
+	if handler := m.resultHandler.Load(); handler != nil {
+		(*handler)(result)
+		return
+	}
+	for _, f := range result.Failures() {
+		slog.Error("failed to send notification",
+			"notifier", f.Notifier,
+			"containerName", result.Event.ContainerName,
+			"action", result.Event.Action,
+			"attempts", f.Attempts,
+			"duration", f.Duration,
+			"error", f.Err,
+		)
 	}
-	return lastErr
 }
 
 func (m *Manager) AddNotifier(n Notifier) {
-	m.notifiers = append(m.notifiers, n)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	q := newDeliveryQueue(m, n)
+	q.start(m.ctx, m.opts.Queue.Workers)
+	m.queues = append(m.queues, q)
+}
+
+// ReplaceNotifier swaps the notifier with the same name for n, or adds n
+// if there is none. Events already queued for the old notifier are still
+// delivered by it; new events go to n, which takes over state such as
+// outstanding Pushover emergencies from it.
+func (m *Manager) ReplaceNotifier(n Notifier) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return
+	}
+	i := slices.IndexFunc(m.queues, func(q *deliveryQueue) bool { return q.notifier.Name() == n.Name() })
+	if h, ok := unwrap(n).(inheritor); ok && i >= 0 {
+		h.inherit(unwrap(m.queues[i].notifier))
+	}
+	q := newDeliveryQueue(m, n)
+	q.start(m.ctx, m.opts.Queue.Workers)
+	if i >= 0 {
+		m.retire(m.queues[i])
+		m.queues[i] = q
+		return
+	}
+	m.queues = append(m.queues, q)
+}
+
+// RemoveNotifier stops sending new events to the named notifier. Events
+// already queued for it are still delivered.
+func (m *Manager) RemoveNotifier(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return
+	}
+	for i, q := range m.queues {
+		if q.notifier.Name() == name {
+			m.queues = slices.Delete(m.queues, i, i+1)
+			m.retire(q)
+			return
+		}
+	}
+}
+
+// retire closes the queue so its workers exit once it is drained, and
+// forgets it then. The caller must hold m.mu.
+func (m *Manager) retire(q *deliveryQueue) {
+	q.close()
+	m.retired = append(m.retired, q)
+	go func() {
+		q.wg.Wait()
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.retired = slices.DeleteFunc(m.retired, func(r *deliveryQueue) bool { return r == q })
+	}()
 }
 
 func (m *Manager) Notifiers() []Notifier {
-	return m.notifiers
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	notifiers := make([]Notifier, 0, len(m.queues))
+	for _, q := range m.queues {
+		notifiers = append(notifiers, q.notifier)
+	}
+	return notifiers
+}
+
+// SetSpool makes the manager keep notifications that could not be
+// delivered in the given dead-letter spool
+func (m *Manager) SetSpool(s *Spool) {
+	m.spool.Store(s)
+}
+
+func (m *Manager) deadLetter(n Notifier, event Event, err error) {
+	spool := m.spool.Load()
+	if spool == nil {
+		return
+	}
+	if spoolErr := spool.Add(n.Name(), event, err); spoolErr != nil {
+		slog.Error("failed to store dead letter",
+			"notifier", n.Name(),
+			"containerName", event.ContainerName,
+			"error", spoolErr,
+		)
+	}
+}
+
+// RedeliverDeadLetters periodically replays the dead-letter spool through
+// the configured notifiers until ctx is done
+func (m *Manager) RedeliverDeadLetters(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			spool := m.spool.Load()
+			if spool == nil {
+				continue
+			}
+			delivered, failed, err := spool.Replay(ctx, m.healthyNotifiers(), m.prepare, "", false)
+			if err != nil && ctx.Err() == nil {
+				slog.Error("failed to redeliver dead letters", "error", err)
+			}
+			if delivered > 0 || failed > 0 {
+				slog.Info("redelivered dead letters", "delivered", delivered, "failed", failed)
+			}
+		}
+	}
+}
+
+// ReplayDeadLetters re-delivers the dead letters in spool through the
+// notifiers, rendered like every other notification. See Spool.Replay.
+func (m *Manager) ReplayDeadLetters(ctx context.Context, spool *Spool, notifier string, parked bool) (delivered, failed int, err error) {
+	return spool.Replay(ctx, m.Notifiers(), m.prepare, notifier, parked)
+}
+
+// healthyNotifiers returns the notifiers whose circuit is closed
+func (m *Manager) healthyNotifiers() []Notifier {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var notifiers []Notifier
+	for _, q := range m.queues {
+		if q.circuit() == CircuitClosed {
+			notifiers = append(notifiers, q.notifier)
+		}
+	}
+	return notifiers
+}
+
+// circuitChanged reports a circuit breaker transition. When a circuit
+// opens or closes again, the other healthy notifiers are told about it so
+// someone knows alerts are going nowhere.
+func (m *Manager) circuitChanged(q *deliveryQueue, from, to CircuitState, err error) {
+	name := q.notifier.Name()
+	switch to {
+	case CircuitOpen:
+		slog.Error("notifier circuit opened, pausing deliveries",
+			"notifier", name,
+			"previous", from,
+			"cooldown", m.opts.CircuitBreaker.Cooldown,
+			"error", err,
+		)
+	case CircuitHalfOpen:
+		slog.Info("notifier circuit half-open, probing", "notifier", name)
+	case CircuitClosed:
+		slog.Info("notifier circuit closed, deliveries resumed", "notifier", name, "previous", from)
+	}
+
+	var meta Event
+	switch {
+	case to == CircuitOpen && from == CircuitClosed:
+		meta = Event{
+			ContainerName: "notidock",
+			Action:        "notifier_down",
+			Timestamp:     time.Now().UTC(),
+			Labels: map[string]string{
+				"notifier": name,
+				"error":    err.Error(),
+			},
+		}
+	case to == CircuitClosed:
+		meta = Event{
+			ContainerName: "notidock",
+			Action:        "notifier_recovered",
+			Timestamp:     time.Now().UTC(),
+			Labels: map[string]string{
+				"notifier": name,
+			},
+		}
+	default:
+		return
+	}
+
+	// Sent asynchronously so the worker never waits on another queue
+	go m.sendMeta(q, meta)
+}
+
+func (m *Manager) sendMeta(source *deliveryQueue, event Event) {
+	m.mu.RLock()
+	if m.closed {
+		m.mu.RUnlock()
+		return
+	}
+	queues := slices.Clone(m.queues)
+	m.mu.RUnlock()
+
+	for _, q := range queues {
+		if q == source || q.circuit() != CircuitClosed {
+			continue
+		}
+		if err := q.enqueue(m.ctx, queuedEvent{event: event}); err != nil {
+			slog.Warn("failed to queue notifier status notification",
+				"notifier", q.notifier.Name(),
+				"error", err,
+			)
+		}
+	}
+}
+
+// Stats returns a snapshot of every notifier's delivery queue
+func (m *Manager) Stats() []QueueStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stats := make([]QueueStats, 0, len(m.queues))
+	for _, q := range m.queues {
+		stats = append(stats, q.stats())
+	}
+	return stats
+}
+
+// Close stops accepting events and waits for the queued ones to be
+// delivered. Deliveries still running when ctx is done are cancelled.
+func (m *Manager) Close(ctx context.Context) error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.closed = true
+	for _, q := range m.queues {
+		q.close()
+	}
+	queues := slices.Concat(m.queues, m.retired)
+	m.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		for _, q := range queues {
+			q.wg.Wait()
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		m.cancel()
+		return nil
+	case <-ctx.Done():
+		m.cancel()
+		<-done
+		return ctx.Err()
+	}
 }
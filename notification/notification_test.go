@@ -0,0 +1,255 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// blockingNotifier blocks every Send until release is closed
+type blockingNotifier struct {
+	*MockNotifier
+	release chan struct{}
+}
+
+func newBlockingNotifier(name string) *blockingNotifier {
+	return &blockingNotifier{
+		MockNotifier: NewMockNotifier(name),
+		release:      make(chan struct{}),
+	}
+}
+
+func (b *blockingNotifier) Send(ctx context.Context, event Event) error {
+	select {
+	case <-b.release:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return b.MockNotifier.Send(ctx, event)
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before deadline")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestManager_Send(t *testing.T) {
+	first := NewMockNotifier("first")
+	second := NewMockNotifier("second")
+	second.SetError(errors.New("webhook revoked"))
+
+	manager := NewManager(ManagerOptions{}, first, second)
+
+	event := Event{ContainerName: "test-container", Action: "die"}
+	if err := manager.Send(context.Background(), event); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := manager.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected error closing manager: %v", err)
+	}
+
+	if got := len(first.GetEvents()); got != 1 {
+		t.Errorf("first notifier received %d events, want 1", got)
+	}
+	if got := len(second.GetEvents()); got != 1 {
+		t.Errorf("second notifier received %d events, want 1", got)
+	}
+
+	stats := manager.Stats()
+	if stats[0].Delivered != 1 || stats[0].Failed != 0 {
+		t.Errorf("unexpected stats for first notifier: %+v", stats[0])
+	}
+	if stats[1].Delivered != 0 || stats[1].Failed != 1 {
+		t.Errorf("unexpected stats for second notifier: %+v", stats[1])
+	}
+
+	if err := manager.Send(context.Background(), event); err == nil {
+		t.Error("expected error sending to closed manager")
+	}
+}
+
+func TestManager_SlowNotifierDoesNotBlockOthers(t *testing.T) {
+	slow := newBlockingNotifier("slow")
+	fast := NewMockNotifier("fast")
+
+	manager := NewManager(ManagerOptions{Queue: QueueOptions{Size: 10}}, slow, fast)
+	defer manager.Close(context.Background())
+	defer close(slow.release)
+
+	for i := 0; i < 5; i++ {
+		if err := manager.Send(context.Background(), Event{ContainerName: "test", Action: "start"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	waitFor(t, func() bool { return len(fast.GetEvents()) == 5 })
+	if got := len(slow.GetEvents()); got != 0 {
+		t.Errorf("slow notifier delivered %d events while blocked", got)
+	}
+}
+
+func TestManager_Overflow(t *testing.T) {
+	send := func(manager *Manager, ctx context.Context, action string) error {
+		return manager.Send(ctx, Event{ContainerName: "test", Action: action})
+	}
+
+	// fill occupies the single worker and then the queue itself
+	fill := func(t *testing.T, policy OverflowPolicy) (*Manager, *blockingNotifier) {
+		notifier := newBlockingNotifier("slow")
+		manager := NewManager(ManagerOptions{Queue: QueueOptions{Size: 2, Overflow: policy}}, notifier)
+		if err := send(manager, context.Background(), "in-flight"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		waitFor(t, func() bool { return manager.Stats()[0].Depth == 0 })
+		for _, action := range []string{"first", "second"} {
+			if err := send(manager, context.Background(), action); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		return manager, notifier
+	}
+
+	actions := func(n *blockingNotifier) []string {
+		var got []string
+		for _, e := range n.GetEvents() {
+			got = append(got, e.Action)
+		}
+		return got
+	}
+
+	t.Run("drop oldest", func(t *testing.T) {
+		manager, notifier := fill(t, OverflowDropOldest)
+		if err := send(manager, context.Background(), "third"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		close(notifier.release)
+		manager.Close(context.Background())
+
+		want := []string{"in-flight", "second", "third"}
+		if got := actions(notifier); len(got) != 3 || got[1] != want[1] || got[2] != want[2] {
+			t.Errorf("delivered %v, want %v", got, want)
+		}
+		if dropped := manager.Stats()[0].Dropped; dropped != 1 {
+			t.Errorf("dropped = %d, want 1", dropped)
+		}
+	})
+
+	t.Run("drop newest", func(t *testing.T) {
+		manager, notifier := fill(t, OverflowDropNewest)
+		if err := send(manager, context.Background(), "third"); !errors.Is(err, ErrQueueFull) {
+			t.Fatalf("expected ErrQueueFull, got %v", err)
+		}
+		close(notifier.release)
+		manager.Close(context.Background())
+
+		want := []string{"in-flight", "first", "second"}
+		if got := actions(notifier); len(got) != 3 || got[1] != want[1] || got[2] != want[2] {
+			t.Errorf("delivered %v, want %v", got, want)
+		}
+	})
+
+	t.Run("block", func(t *testing.T) {
+		manager, notifier := fill(t, OverflowBlock)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if err := send(manager, ctx, "third"); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected deadline exceeded, got %v", err)
+		}
+		close(notifier.release)
+		manager.Close(context.Background())
+	})
+
+	t.Run("block does not hold up reloads or close", func(t *testing.T) {
+		manager, _ := fill(t, OverflowBlock)
+		sent := make(chan error, 1)
+		go func() { sent <- send(manager, context.Background(), "third") }()
+		// Let the send block on the full queue
+		time.Sleep(20 * time.Millisecond)
+
+		manager.ReplaceNotifier(NewMockNotifier("other"))
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if err := manager.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Close() = %v, want the deadline exceeded", err)
+		}
+		if err := <-sent; !errors.Is(err, errQueueClosed) {
+			t.Errorf("blocked send returned %v, want errQueueClosed", err)
+		}
+	})
+}
+
+func TestManager_CloseCancelsPendingDeliveries(t *testing.T) {
+	notifier := newBlockingNotifier("stuck")
+	manager := NewManager(ManagerOptions{}, notifier)
+	manager.Send(context.Background(), Event{ContainerName: "test", Action: "die"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := manager.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestManager_ReplaceNotifier(t *testing.T) {
+	old := newBlockingNotifier("alerts")
+	other := NewMockNotifier("events")
+	manager := NewManager(ManagerOptions{}, old, other)
+
+	manager.Send(context.Background(), Event{ContainerName: "before", Action: "die"})
+
+	replacement := NewMockNotifier("alerts")
+	manager.ReplaceNotifier(replacement)
+	manager.RemoveNotifier("events")
+	added := NewMockNotifier("paging")
+	manager.ReplaceNotifier(added)
+
+	manager.Send(context.Background(), Event{ContainerName: "after", Action: "die"})
+	close(old.release)
+	if err := manager.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	var names []string
+	for _, n := range manager.Notifiers() {
+		names = append(names, n.Name())
+	}
+	if len(names) != 2 || names[0] != "alerts" || names[1] != "paging" {
+		t.Errorf("Notifiers() = %v, want [alerts paging]", names)
+	}
+	if got := old.GetEvents(); len(got) != 1 || got[0].ContainerName != "before" {
+		t.Errorf("replaced notifier should deliver the events queued before, got %+v", got)
+	}
+	if got := replacement.GetEvents(); len(got) != 1 || got[0].ContainerName != "after" {
+		t.Errorf("replacement received %+v", got)
+	}
+	if got := other.GetEvents(); len(got) != 1 || got[0].ContainerName != "before" {
+		t.Errorf("removed notifier received %+v", got)
+	}
+	if got := added.GetEvents(); len(got) != 1 {
+		t.Errorf("added notifier received %+v", got)
+	}
+}
+
+func TestManager_RetiredQueuesAreForgotten(t *testing.T) {
+	manager := NewManager(ManagerOptions{}, NewMockNotifier("alerts"))
+	defer manager.Close(context.Background())
+
+	for i := 0; i < 20; i++ {
+		manager.Send(context.Background(), Event{ContainerName: "test", Action: "die"})
+		manager.ReplaceNotifier(NewMockNotifier("alerts"))
+	}
+	manager.RemoveNotifier("alerts")
+
+	waitFor(t, func() bool {
+		manager.mu.RLock()
+		defer manager.mu.RUnlock()
+		return len(manager.retired) == 0
+	})
+}
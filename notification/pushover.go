@@ -0,0 +1,346 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	pushoverAPIURL = "https://api.pushover.net/1"
+
+	PushoverPriorityNormal    = 0
+	PushoverPriorityHigh      = 1
+	PushoverPriorityEmergency = 2
+
+	DefaultPushoverRetry  = 60 * time.Second
+	DefaultPushoverExpire = time.Hour
+
+	// Limits enforced by the Pushover API for emergency messages
+	minPushoverRetry  = 30 * time.Second
+	maxPushoverExpire = 3 * time.Hour
+)
+
+// PushoverNotifier sends events through the Pushover API. OOM kills and
+// unhealthy containers are sent with emergency priority, which repeats the
+// alert until it is acknowledged or the container recovers.
+type PushoverNotifier struct {
+	name    string
+	baseURL string
+	token   string
+	user    string
+	device  string
+	sounds  map[string]string
+	retry   time.Duration
+	expire  time.Duration
+	client  *http.Client
+
+	// receipts is taken over by the notifier replacing this one on a
+	// reload, so outstanding emergencies can still be cancelled
+	receipts *pushoverReceipts
+}
+
+// pushoverReceipts holds the receipts of the outstanding emergency alerts
+// by container name
+type pushoverReceipts struct {
+	mu          sync.Mutex
+	byContainer map[string]pushoverReceipt
+}
+
+// pushoverReceipt is an emergency alert and the application token it was
+// sent with, which is needed to cancel it
+type pushoverReceipt struct {
+	id    string
+	token string
+}
+
+func newPushoverReceipts() *pushoverReceipts {
+	return &pushoverReceipts{byContainer: make(map[string]pushoverReceipt)}
+}
+
+// swap stores the container's receipt and returns the one it replaces
+func (r *pushoverReceipts) swap(containerName string, receipt pushoverReceipt) (pushoverReceipt, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	previous, ok := r.byContainer[containerName]
+	r.byContainer[containerName] = receipt
+	return previous, ok
+}
+
+// take removes and returns the container's receipt
+func (r *pushoverReceipts) take(containerName string) (pushoverReceipt, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	receipt, ok := r.byContainer[containerName]
+	delete(r.byContainer, containerName)
+	return receipt, ok
+}
+
+type pushoverResponse struct {
+	Status  int      `json:"status"`
+	Request string   `json:"request"`
+	Receipt string   `json:"receipt"`
+	Errors  []string `json:"errors"`
+}
+
+// PushoverOptions configures a Pushover notifier
+type PushoverOptions struct {
+	// Name identifies the notifier in routes and logs, "pushover" by default
+	Name   string
+	Token  string
+	User   string
+	Device string
+	// Sounds maps event actions to Pushover sounds
+	Sounds map[string]string
+	// Retry and Expire control emergency alerts; zero uses the defaults
+	Retry  time.Duration
+	Expire time.Duration
+}
+
+func NewPushoverNotifier() (*PushoverNotifier, error) {
+	var env envVars
+	token := env.get("NOTIDOCK_PUSHOVER_TOKEN")
+	user := env.get("NOTIDOCK_PUSHOVER_USER")
+	rawSounds := env.get("NOTIDOCK_PUSHOVER_SOUNDS")
+	device := env.get("NOTIDOCK_PUSHOVER_DEVICE")
+	retry := env.get("NOTIDOCK_PUSHOVER_RETRY")
+	expire := env.get("NOTIDOCK_PUSHOVER_EXPIRE")
+	if env.err != nil {
+		return nil, env.err
+	}
+	if token == "" && user == "" {
+		return nil, ErrNotConfigured
+	}
+	if token == "" || user == "" {
+		return nil, errors.New("both NOTIDOCK_PUSHOVER_TOKEN and NOTIDOCK_PUSHOVER_USER must be set")
+	}
+
+	sounds, err := parsePushoverSounds(rawSounds)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := PushoverOptions{
+		Token:  token,
+		User:   user,
+		Device: device,
+		Sounds: sounds,
+	}
+	if retry != "" {
+		if opts.Retry, err = time.ParseDuration(retry); err != nil || opts.Retry < minPushoverRetry {
+			return nil, fmt.Errorf("invalid NOTIDOCK_PUSHOVER_RETRY %q: must be a duration of at least %s", retry, minPushoverRetry)
+		}
+	}
+	if expire != "" {
+		if opts.Expire, err = time.ParseDuration(expire); err != nil || opts.Expire <= 0 || opts.Expire > maxPushoverExpire {
+			return nil, fmt.Errorf("invalid NOTIDOCK_PUSHOVER_EXPIRE %q: must be a positive duration of at most %s", expire, maxPushoverExpire)
+		}
+	}
+	return NewPushoverNotifierWithOptions(opts)
+}
+
+// NewPushoverNotifierWithOptions creates a Pushover notifier from explicit
+// settings
+func NewPushoverNotifierWithOptions(opts PushoverOptions) (*PushoverNotifier, error) {
+	if opts.Token == "" || opts.User == "" {
+		return nil, errors.New("both a Pushover token and user key must be set")
+	}
+	if opts.Retry == 0 {
+		opts.Retry = DefaultPushoverRetry
+	}
+	if opts.Expire == 0 {
+		opts.Expire = DefaultPushoverExpire
+	}
+	if opts.Retry < minPushoverRetry {
+		return nil, fmt.Errorf("invalid Pushover retry %s: must be at least %s", opts.Retry, minPushoverRetry)
+	}
+	if opts.Expire < 0 || opts.Expire > maxPushoverExpire {
+		return nil, fmt.Errorf("invalid Pushover expire %s: must be a positive duration of at most %s", opts.Expire, maxPushoverExpire)
+	}
+	if opts.Name == "" {
+		opts.Name = "pushover"
+	}
+
+	return &PushoverNotifier{
+		name:     opts.Name,
+		baseURL:  pushoverAPIURL,
+		token:    opts.Token,
+		user:     opts.User,
+		device:   opts.Device,
+		sounds:   opts.Sounds,
+		retry:    opts.Retry,
+		expire:   opts.Expire,
+		client:   &http.Client{},
+		receipts: newPushoverReceipts(),
+	}, nil
+}
+
+func parsePushoverSounds(s string) (map[string]string, error) {
+	sounds := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		action, sound, ok := strings.Cut(pair, "=")
+		action, sound = strings.TrimSpace(action), strings.TrimSpace(sound)
+		if !ok || action == "" || sound == "" {
+			return nil, fmt.Errorf("invalid NOTIDOCK_PUSHOVER_SOUNDS entry %q: must be action=sound", pair)
+		}
+		sounds[action] = sound
+	}
+	return sounds, nil
+}
+
+func (p *PushoverNotifier) Name() string {
+	return p.name
+}
+
+// Send implements the Notifier interface for Pushover
+func (p *PushoverNotifier) Send(ctx context.Context, event Event) error {
+	if isRecovery(event) {
+		p.cancelEmergency(ctx, event.ContainerName)
+	}
+
+	priority := PushoverPriorityNormal
+	switch EventSeverity(event) {
+	case SeverityCritical:
+		priority = PushoverPriorityEmergency
+	case SeverityWarning:
+		priority = PushoverPriorityHigh
+	}
+
+	form := url.Values{}
+	form.Set("token", p.token)
+	form.Set("user", p.user)
+	event = withMessage(event)
+	form.Set("title", event.Title)
+	form.Set("message", event.Body)
+	form.Set("priority", strconv.Itoa(priority))
+	if p.device != "" {
+		form.Set("device", p.device)
+	}
+	if sound, ok := p.sounds[event.Action]; ok {
+		form.Set("sound", sound)
+	}
+	if !event.Timestamp.IsZero() {
+		form.Set("timestamp", strconv.FormatInt(event.Timestamp.Unix(), 10))
+	}
+	if priority == PushoverPriorityEmergency {
+		form.Set("retry", strconv.Itoa(int(p.retry.Seconds())))
+		form.Set("expire", strconv.Itoa(int(p.expire.Seconds())))
+	}
+
+	resp, err := p.post(ctx, p.baseURL+"/messages.json", form)
+	if err != nil {
+		return fmt.Errorf("failed to send pushover notification: %w", err)
+	}
+
+	if priority == PushoverPriorityEmergency && resp.Receipt != "" {
+		// Only one emergency per container keeps retrying
+		receipt := pushoverReceipt{id: resp.Receipt, token: p.token}
+		if previous, ok := p.receipts.swap(event.ContainerName, receipt); ok {
+			p.cancelReceipt(ctx, event.ContainerName, previous)
+		}
+	}
+
+	return nil
+}
+
+// Recovered cancels the outstanding emergency alert of a container that
+// recovered, when the recovery event is routed to other notifiers
+func (p *PushoverNotifier) Recovered(ctx context.Context, event Event) {
+	p.cancelEmergency(ctx, event.ContainerName)
+}
+
+// inherit takes over the outstanding emergency alerts of the Pushover
+// notifier this one replaces
+func (p *PushoverNotifier) inherit(old Notifier) {
+	if old, ok := old.(*PushoverNotifier); ok {
+		p.receipts = old.receipts
+	}
+}
+
+// cancelEmergency stops the retries of an outstanding emergency alert
+// for the container, if there is one
+func (p *PushoverNotifier) cancelEmergency(ctx context.Context, containerName string) {
+	if receipt, ok := p.receipts.take(containerName); ok {
+		p.cancelReceipt(ctx, containerName, receipt)
+	}
+}
+
+func (p *PushoverNotifier) cancelReceipt(ctx context.Context, containerName string, receipt pushoverReceipt) {
+	form := url.Values{}
+	form.Set("token", receipt.token)
+
+	if _, err := p.post(ctx, p.baseURL+"/receipts/"+url.PathEscape(receipt.id)+"/cancel.json", form); err != nil {
+		slog.Warn("failed to cancel pushover emergency alert",
+			"error", err,
+			"containerName", containerName,
+		)
+	}
+}
+
+func (p *PushoverNotifier) post(ctx context.Context, endpoint string, form url.Values) (*pushoverResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body pushoverResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("failed to decode pushover response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || body.Status != 1 {
+		err := fmt.Errorf("pushover request failed with status code: %d", resp.StatusCode)
+		if len(body.Errors) > 0 {
+			err = fmt.Errorf("pushover request failed with status code %d: %s", resp.StatusCode, strings.Join(body.Errors, "; "))
+		}
+		if resp.StatusCode == http.StatusOK {
+			return nil, Permanent(err)
+		}
+		return nil, newHTTPError(resp, err)
+	}
+
+	return &body, nil
+}
+
+// isCriticalFailure reports whether the event warrants an emergency alert:
+// an OOM kill, a crash loop or a container turning unhealthy. An exit code
+// of 137 is not enough, since any SIGKILL such as docker kill or a stop
+// timeout exits with it.
+func isCriticalFailure(event Event) bool {
+	switch event.Action {
+	case "oom", "crash_loop":
+		return true
+	case "health_status", "health_flapping_stopped":
+		return event.Labels["health_status"] == "unhealthy"
+	}
+	return false
+}
+
+// isRecovery reports whether the event shows the container working again
+func isRecovery(event Event) bool {
+	switch event.Action {
+	case "start", "crash_loop_resolved":
+		return true
+	case "health_status", "health_flapping_stopped":
+		return event.Labels["health_status"] == "healthy"
+	}
+	return false
+}
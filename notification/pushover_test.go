@@ -0,0 +1,315 @@
+package notification
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewPushoverNotifier(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr bool
+	}{
+		{
+			name: "valid configuration",
+			env: map[string]string{
+				"NOTIDOCK_PUSHOVER_TOKEN":  "app-token",
+				"NOTIDOCK_PUSHOVER_USER":   "group-key",
+				"NOTIDOCK_PUSHOVER_SOUNDS": "die=falling, oom=siren",
+				"NOTIDOCK_PUSHOVER_RETRY":  "2m",
+				"NOTIDOCK_PUSHOVER_EXPIRE": "1h",
+			},
+		},
+		{
+			name: "missing user key",
+			env: map[string]string{
+				"NOTIDOCK_PUSHOVER_TOKEN": "app-token",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid sounds",
+			env: map[string]string{
+				"NOTIDOCK_PUSHOVER_TOKEN":  "app-token",
+				"NOTIDOCK_PUSHOVER_USER":   "group-key",
+				"NOTIDOCK_PUSHOVER_SOUNDS": "siren",
+			},
+			wantErr: true,
+		},
+		{
+			name: "retry below api minimum",
+			env: map[string]string{
+				"NOTIDOCK_PUSHOVER_TOKEN": "app-token",
+				"NOTIDOCK_PUSHOVER_USER":  "group-key",
+				"NOTIDOCK_PUSHOVER_RETRY": "10s",
+			},
+			wantErr: true,
+		},
+		{
+			name: "expire above api maximum",
+			env: map[string]string{
+				"NOTIDOCK_PUSHOVER_TOKEN":  "app-token",
+				"NOTIDOCK_PUSHOVER_USER":   "group-key",
+				"NOTIDOCK_PUSHOVER_EXPIRE": "4h",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			notifier, err := NewPushoverNotifier()
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if notifier.sounds["oom"] != "siren" || notifier.sounds["die"] != "falling" {
+				t.Errorf("unexpected sounds: %v", notifier.sounds)
+			}
+		})
+	}
+
+	t.Run("not configured", func(t *testing.T) {
+		if _, err := NewPushoverNotifier(); err != ErrNotConfigured {
+			t.Errorf("expected ErrNotConfigured, got %v", err)
+		}
+	})
+}
+
+func TestPushoverNotifier_Send(t *testing.T) {
+	var mu sync.Mutex
+	var messages []url.Values
+	var cancelled []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("failed to parse form: %v", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch {
+		case r.URL.Path == "/messages.json":
+			messages = append(messages, r.PostForm)
+			if r.PostForm.Get("priority") == "2" {
+				w.Write([]byte(`{"status":1,"request":"req","receipt":"receipt-1"}`))
+				return
+			}
+			w.Write([]byte(`{"status":1,"request":"req"}`))
+		case strings.HasPrefix(r.URL.Path, "/receipts/"):
+			cancelled = append(cancelled, strings.Split(r.URL.Path, "/")[2])
+			w.Write([]byte(`{"status":1,"request":"req"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	notifier := &PushoverNotifier{
+		baseURL:  server.URL,
+		token:    "app-token",
+		user:     "user-key",
+		device:   "phone",
+		sounds:   map[string]string{"oom": "siren"},
+		retry:    DefaultPushoverRetry,
+		expire:   DefaultPushoverExpire,
+		client:   server.Client(),
+		receipts: newPushoverReceipts(),
+	}
+
+	oom := Event{
+		ContainerName: "payments-api",
+		Action:        "oom",
+		Time:          "2024-12-14T17:34:36Z",
+		Timestamp:     time.Date(2024, 12, 14, 17, 34, 36, 0, time.UTC),
+	}
+	if err := notifier.Send(context.Background(), oom); err != nil {
+		t.Fatalf("failed to send notification: %v", err)
+	}
+
+	msg := messages[0]
+	for key, want := range map[string]string{
+		"token":     "app-token",
+		"user":      "user-key",
+		"device":    "phone",
+		"priority":  "2",
+		"sound":     "siren",
+		"retry":     "60",
+		"expire":    "3600",
+		"timestamp": "1734197676",
+		"title":     "Container Event: payments-api",
+	} {
+		if got := msg.Get(key); got != want {
+			t.Errorf("form %s = %q, want %q", key, got, want)
+		}
+	}
+	if got := notifier.receipts.byContainer["payments-api"]; got.id != "receipt-1" || got.token != "app-token" {
+		t.Errorf("emergency receipt not tracked: %v", notifier.receipts.byContainer)
+	}
+
+	// A regular event does not touch the receipt
+	if err := notifier.Send(context.Background(), Event{ContainerName: "other", Action: "create"}); err != nil {
+		t.Fatalf("failed to send notification: %v", err)
+	}
+	if got := messages[1].Get("priority"); got != "0" {
+		t.Errorf("priority = %q, want 0", got)
+	}
+	if len(cancelled) != 0 {
+		t.Errorf("unexpected cancellation: %v", cancelled)
+	}
+
+	// Recovery cancels the outstanding emergency alert
+	recovered := Event{ContainerName: "payments-api", Action: "start"}
+	if err := notifier.Send(context.Background(), recovered); err != nil {
+		t.Fatalf("failed to send notification: %v", err)
+	}
+	if len(cancelled) != 1 || cancelled[0] != "receipt-1" {
+		t.Errorf("expected receipt-1 to be cancelled, got %v", cancelled)
+	}
+	if _, ok := notifier.receipts.byContainer["payments-api"]; ok {
+		t.Error("receipt should be forgotten after recovery")
+	}
+}
+
+func TestPushoverNotifier_SendError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":0,"errors":["user identifier is invalid"]}`))
+	}))
+	defer server.Close()
+
+	notifier := &PushoverNotifier{
+		baseURL:  server.URL,
+		token:    "app-token",
+		user:     "bad",
+		client:   server.Client(),
+		receipts: newPushoverReceipts(),
+	}
+
+	err := notifier.Send(context.Background(), Event{ContainerName: "test", Action: "die"})
+	if err == nil || !strings.Contains(err.Error(), "user identifier is invalid") {
+		t.Errorf("expected api error, got %v", err)
+	}
+}
+
+func TestIsCriticalFailure(t *testing.T) {
+	tests := []struct {
+		name  string
+		event Event
+		want  bool
+	}{
+		{"oom event", Event{Action: "oom"}, true},
+		{"sigkill exit", Event{Action: "die", Labels: map[string]string{"exitCode": "137"}}, false},
+		{"error exit", Event{Action: "die", Labels: map[string]string{"exitCode": "1"}}, false},
+		{"unhealthy", Event{Action: "health_status", Labels: map[string]string{"health_status": "unhealthy"}}, true},
+		{"healthy", Event{Action: "health_status", Labels: map[string]string{"health_status": "healthy"}}, false},
+		{"start", Event{Action: "start"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isCriticalFailure(tt.event); got != tt.want {
+				t.Errorf("isCriticalFailure() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// pushoverManager runs a Pushover notifier and a Slack mock the way main.go
+// does, wrapped by WithRetry, with emergencies routed to Pushover and
+// everything else to Slack. It returns the cancelled receipts by the token
+// they were cancelled with.
+func pushoverManager(t *testing.T) (*Manager, func(token string) Notifier, *MockNotifier, func() map[string]string) {
+	t.Helper()
+	var mu sync.Mutex
+	cancelled := map[string]string{} // receipt -> token
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		mu.Lock()
+		defer mu.Unlock()
+
+		if strings.HasPrefix(r.URL.Path, "/receipts/") {
+			cancelled[strings.Split(r.URL.Path, "/")[2]] = r.PostForm.Get("token")
+		}
+		w.Write([]byte(`{"status":1,"request":"req","receipt":"receipt-1"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	pushover := func(token string) Notifier {
+		return WithRetry(&PushoverNotifier{
+			name:     "pushover",
+			baseURL:  server.URL,
+			token:    token,
+			user:     "user-key",
+			retry:    DefaultPushoverRetry,
+			expire:   DefaultPushoverExpire,
+			client:   server.Client(),
+			receipts: newPushoverReceipts(),
+		}, RetryPolicy{})
+	}
+	slack := NewMockNotifier("slack")
+
+	manager := NewManager(ManagerOptions{}, pushover("old-token"), WithRetry(slack, RetryPolicy{}))
+	if err := manager.SetRoutes([]Route{
+		{Match: RouteMatch{Actions: []string{"oom"}}, Notifiers: []string{"pushover"}},
+		{Notifiers: []string{"slack"}},
+	}); err != nil {
+		t.Fatalf("SetRoutes() error = %v", err)
+	}
+
+	manager.Send(context.Background(), Event{ContainerName: "payments-api", Action: "oom"})
+	// Wait for the emergency to be sent
+	for i := 0; i < 100 && manager.Stats()[0].Delivered == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return manager, pushover, slack, func() map[string]string {
+		mu.Lock()
+		defer mu.Unlock()
+		return maps.Clone(cancelled)
+	}
+}
+
+func TestPushoverNotifier_CancelsRecoveryRoutedElsewhere(t *testing.T) {
+	manager, _, slack, cancelled := pushoverManager(t)
+
+	manager.Send(context.Background(), Event{ContainerName: "payments-api", Action: "start"})
+	manager.Close(context.Background())
+
+	if token, ok := cancelled()["receipt-1"]; !ok || token != "old-token" {
+		t.Errorf("expected receipt-1 to be cancelled, got %v", cancelled())
+	}
+	if events := slack.GetEvents(); len(events) != 1 || events[0].Action != "start" {
+		t.Errorf("slack received %v, want the start event", events)
+	}
+}
+
+func TestPushoverNotifier_InheritsReceiptsOnReload(t *testing.T) {
+	manager, pushover, _, cancelled := pushoverManager(t)
+
+	manager.ReplaceNotifier(pushover("new-token"))
+	manager.Send(context.Background(), Event{ContainerName: "payments-api", Action: "start"})
+	manager.Close(context.Background())
+
+	if token, ok := cancelled()["receipt-1"]; !ok || token != "old-token" {
+		t.Errorf("expected receipt-1 to be cancelled with the token it was sent with, got %v", cancelled())
+	}
+}
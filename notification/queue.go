@@ -0,0 +1,251 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// OverflowPolicy decides what happens when an event is sent to a
+// notifier whose queue is full
+type OverflowPolicy string
+
+const (
+	// OverflowDropOldest discards the oldest queued event to make room
+	OverflowDropOldest OverflowPolicy = "drop_oldest"
+	// OverflowDropNewest discards the event being sent
+	OverflowDropNewest OverflowPolicy = "drop_newest"
+	// OverflowBlock waits until there is room, the context is done or the
+	// queue is closed
+	OverflowBlock OverflowPolicy = "block"
+)
+
+// Default queue settings
+const (
+	DefaultQueueSize    = 100
+	DefaultQueueWorkers = 1
+)
+
+// ErrQueueFull is returned when an event is dropped because a notifier's
+// queue is full
+var ErrQueueFull = errors.New("notification queue full")
+
+// errQueueClosed is returned when an event is sent to a queue that was
+// closed, because the manager is closing or the notifier was replaced
+var errQueueClosed = errors.New("notification queue closed")
+
+// QueueOptions configures the per-notifier delivery queues
+type QueueOptions struct {
+	Size     int
+	Workers  int
+	Overflow OverflowPolicy
+}
+
+// QueueStats is a snapshot of a notifier's delivery queue
+type QueueStats struct {
+	Notifier  string
+	Depth     int
+	Capacity  int
+	Delivered uint64
+	Failed    uint64
+	Dropped   uint64
+	// Rejected counts events not attempted because the circuit was open
+	Rejected  uint64
+	Circuit   CircuitState
+	LastError error
+}
+
+// queuedEvent is an event waiting for delivery, with the pending result
+// it reports to. Recovery events are only handed to the notifier's
+// RecoveryObserver, since the event was routed elsewhere.
+type queuedEvent struct {
+	event    Event
+	pending  *pendingSend
+	recovery bool
+}
+
+// deliveryQueue buffers events for a single notifier and delivers them
+// from its own workers, so a slow notifier only delays itself
+type deliveryQueue struct {
+	manager  *Manager
+	notifier Notifier
+	events   chan queuedEvent
+	overflow OverflowPolicy
+	breaker  *circuitBreaker // nil when circuit breaking is disabled
+	wg       sync.WaitGroup
+
+	// sendMu is held for reading while sending to events and for writing
+	// to close it. closing is closed first, so senders blocked on a full
+	// queue give up instead of holding up the close.
+	sendMu  sync.RWMutex
+	closing chan struct{}
+	closed  bool
+
+	delivered atomic.Uint64
+	failed    atomic.Uint64
+	dropped   atomic.Uint64
+	rejected  atomic.Uint64
+}
+
+func newDeliveryQueue(m *Manager, n Notifier) *deliveryQueue {
+	q := &deliveryQueue{
+		manager:  m,
+		notifier: n,
+		events:   make(chan queuedEvent, m.opts.Queue.Size),
+		overflow: m.opts.Queue.Overflow,
+		closing:  make(chan struct{}),
+	}
+	if m.opts.CircuitBreaker.FailureThreshold > 0 {
+		q.breaker = newCircuitBreaker(m.opts.CircuitBreaker)
+	}
+	return q
+}
+
+func (q *deliveryQueue) start(ctx context.Context, workers int) {
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+		go func() {
+			defer q.wg.Done()
+			for queued := range q.events {
+				if queued.recovery {
+					unwrap(q.notifier).(RecoveryObserver).Recovered(ctx, queued.event)
+					continue
+				}
+				queued.pending.record(q.deliver(ctx, queued.event))
+			}
+		}()
+	}
+}
+
+func (q *deliveryQueue) deliver(ctx context.Context, event Event) NotifierResult {
+	result := NotifierResult{Notifier: q.notifier.Name()}
+
+	if q.breaker != nil {
+		allowed, probing := q.breaker.allow()
+		if !allowed {
+			q.rejected.Add(1)
+			result.Err = ErrCircuitOpen
+			q.manager.deadLetter(q.notifier, event, ErrCircuitOpen)
+			return result
+		}
+		if probing {
+			q.manager.circuitChanged(q, CircuitOpen, CircuitHalfOpen, nil)
+		}
+	}
+
+	attemptCtx, attempts := withAttemptCounter(ctx)
+	start := time.Now()
+	err := q.notifier.Send(attemptCtx, q.manager.prepare(event))
+	result.Duration = time.Since(start)
+	result.Attempts = max(int(attempts.Load()), 1)
+	result.Err = err
+
+	if err != nil {
+		q.failed.Add(1)
+		// Failures caused by shutting down say nothing about the notifier
+		if q.breaker != nil && ctx.Err() == nil {
+			if previous, current := q.breaker.failure(err); previous != current {
+				q.manager.circuitChanged(q, previous, current, err)
+			}
+		}
+		q.manager.deadLetter(q.notifier, event, err)
+		return result
+	}
+
+	q.delivered.Add(1)
+	if q.breaker != nil {
+		if previous := q.breaker.success(); previous != CircuitClosed {
+			q.manager.circuitChanged(q, previous, CircuitClosed, nil)
+		}
+	}
+	return result
+}
+
+// close stops the queue accepting events, so its workers exit once it is
+// drained. It is called once, by the manager holding m.mu.
+func (q *deliveryQueue) close() {
+	close(q.closing)
+	q.sendMu.Lock()
+	defer q.sendMu.Unlock()
+	q.closed = true
+	close(q.events)
+}
+
+// enqueue adds the event to the queue, applying the overflow policy
+// when the queue is full
+func (q *deliveryQueue) enqueue(ctx context.Context, queued queuedEvent) error {
+	q.sendMu.RLock()
+	defer q.sendMu.RUnlock()
+
+	if q.closed {
+		return errQueueClosed
+	}
+	select {
+	case q.events <- queued:
+		return nil
+	default:
+	}
+
+	switch q.overflow {
+	case OverflowBlock:
+		select {
+		case q.events <- queued:
+			return nil
+		case <-ctx.Done():
+			q.dropped.Add(1)
+			return ctx.Err()
+		case <-q.closing:
+			q.dropped.Add(1)
+			return errQueueClosed
+		}
+	case OverflowDropNewest:
+		q.dropped.Add(1)
+		return ErrQueueFull
+	default:
+		for {
+			select {
+			case dropped := <-q.events:
+				q.dropped.Add(1)
+				slog.Warn("notification queue full, dropped oldest event",
+					"notifier", q.notifier.Name(),
+					"containerName", dropped.event.ContainerName,
+					"action", dropped.event.Action,
+				)
+				dropped.pending.record(NotifierResult{Notifier: q.notifier.Name(), Err: ErrQueueFull})
+			default:
+			}
+			select {
+			case q.events <- queued:
+				return nil
+			default:
+			}
+		}
+	}
+}
+
+func (q *deliveryQueue) circuit() CircuitState {
+	if q.breaker == nil {
+		return CircuitClosed
+	}
+	state, _ := q.breaker.snapshot()
+	return state
+}
+
+func (q *deliveryQueue) stats() QueueStats {
+	stats := QueueStats{
+		Notifier:  q.notifier.Name(),
+		Depth:     len(q.events),
+		Capacity:  cap(q.events),
+		Delivered: q.delivered.Load(),
+		Failed:    q.failed.Load(),
+		Dropped:   q.dropped.Load(),
+		Rejected:  q.rejected.Load(),
+	}
+	if q.breaker != nil {
+		stats.Circuit, stats.LastError = q.breaker.snapshot()
+	}
+	return stats
+}
@@ -0,0 +1,256 @@
+package notification
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultRedisStream = "notidock:events"
+	DefaultRedisMaxLen = 10000
+	defaultRedisPort   = "6379"
+	redisTimeout       = 5 * time.Second
+)
+
+// RedisNotifier appends events to a Redis Stream with XADD, trimming
+// the stream to roughly maxLen entries
+type RedisNotifier struct {
+	name     string
+	addr     string
+	useTLS   bool
+	host     string
+	username string
+	password string
+	db       int
+	stream   string
+	maxLen   int
+	timeout  time.Duration
+}
+
+// RedisOptions configures a Redis notifier
+type RedisOptions struct {
+	// Name identifies the notifier in routes and logs, "redis" by default
+	Name   string
+	URL    string
+	Stream string
+	// MaxLen is the approximate stream length to trim to; 0 disables
+	// trimming
+	MaxLen int
+}
+
+func NewRedisNotifier() (*RedisNotifier, error) {
+	var env envVars
+	opts := RedisOptions{
+		URL:    env.get("NOTIDOCK_REDIS_URL"),
+		Stream: env.get("NOTIDOCK_REDIS_STREAM"),
+		MaxLen: DefaultRedisMaxLen,
+	}
+	maxLen := env.get("NOTIDOCK_REDIS_MAXLEN")
+	if env.err != nil {
+		return nil, env.err
+	}
+	if opts.URL == "" {
+		return nil, ErrNotConfigured
+	}
+	if maxLen != "" {
+		var err error
+		if opts.MaxLen, err = strconv.Atoi(maxLen); err != nil || opts.MaxLen < 0 {
+			return nil, fmt.Errorf("invalid NOTIDOCK_REDIS_MAXLEN %q: must be a non-negative integer", maxLen)
+		}
+	}
+	return NewRedisNotifierWithOptions(opts)
+}
+
+// NewRedisNotifierWithOptions creates a Redis notifier from explicit settings
+func NewRedisNotifierWithOptions(opts RedisOptions) (*RedisNotifier, error) {
+	parsedURL, err := url.Parse(opts.URL)
+	if err != nil || (parsedURL.Scheme != "redis" && parsedURL.Scheme != "rediss") || parsedURL.Hostname() == "" {
+		return nil, errors.New("invalid Redis URL: must be redis://[user:password@]host[:port][/db] or rediss://...")
+	}
+	if opts.MaxLen < 0 {
+		return nil, fmt.Errorf("invalid Redis max length %d: must not be negative", opts.MaxLen)
+	}
+
+	port := parsedURL.Port()
+	if port == "" {
+		port = defaultRedisPort
+	}
+
+	r := &RedisNotifier{
+		name:    opts.Name,
+		addr:    net.JoinHostPort(parsedURL.Hostname(), port),
+		useTLS:  parsedURL.Scheme == "rediss",
+		host:    parsedURL.Hostname(),
+		stream:  opts.Stream,
+		maxLen:  opts.MaxLen,
+		timeout: redisTimeout,
+	}
+	if r.name == "" {
+		r.name = "redis"
+	}
+	if parsedURL.User != nil {
+		r.username = parsedURL.User.Username()
+		r.password, _ = parsedURL.User.Password()
+	}
+	if db := strings.TrimPrefix(parsedURL.Path, "/"); db != "" {
+		if r.db, err = strconv.Atoi(db); err != nil || r.db < 0 {
+			return nil, fmt.Errorf("invalid Redis database %q", db)
+		}
+	}
+	if r.stream == "" {
+		r.stream = DefaultRedisStream
+	}
+
+	return r, nil
+}
+
+func (r *RedisNotifier) Name() string {
+	return r.name
+}
+
+// Send implements the Notifier interface for Redis Streams
+func (r *RedisNotifier) Send(ctx context.Context, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal redis message: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", r.addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to redis: %w", err)
+	}
+	defer conn.Close()
+
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	if r.useTLS {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: r.host})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return fmt.Errorf("redis tls handshake failed: %w", err)
+		}
+		conn = tlsConn
+	}
+
+	var commands [][]string
+	if r.password != "" {
+		if r.username != "" {
+			commands = append(commands, []string{"AUTH", r.username, r.password})
+		} else {
+			commands = append(commands, []string{"AUTH", r.password})
+		}
+	}
+	if r.db != 0 {
+		commands = append(commands, []string{"SELECT", strconv.Itoa(r.db)})
+	}
+
+	xadd := []string{"XADD", r.stream}
+	if r.maxLen > 0 {
+		xadd = append(xadd, "MAXLEN", "~", strconv.Itoa(r.maxLen))
+	}
+	xadd = append(xadd, "*",
+		"container", event.ContainerName,
+		"action", event.Action,
+		"event", string(payload),
+	)
+	commands = append(commands, xadd)
+
+	// Pipeline all commands and read the replies in order
+	var buf strings.Builder
+	for _, cmd := range commands {
+		writeRESPCommand(&buf, cmd)
+	}
+	if _, err := io.WriteString(conn, buf.String()); err != nil {
+		return fmt.Errorf("failed to send redis command: %w", contextErr(ctx, err))
+	}
+
+	reader := bufio.NewReader(conn)
+	for _, cmd := range commands {
+		if _, err := readRESPReply(reader); err != nil {
+			err = fmt.Errorf("redis %s failed: %w", cmd[0], contextErr(ctx, err))
+			var reply redisError
+			if errors.As(err, &reply) && !reply.retryable() {
+				return Permanent(err)
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeRESPCommand(w io.StringWriter, args []string) {
+	w.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		w.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n")
+	}
+}
+
+// redisError is an error reply sent by the server
+type redisError string
+
+func (e redisError) Error() string {
+	return string(e)
+}
+
+// redisTransientErrors are the error replies of a server that is loading,
+// busy or failing over, which may succeed when sent again
+var redisTransientErrors = []string{"LOADING", "BUSY", "TRYAGAIN", "MASTERDOWN", "CLUSTERDOWN", "READONLY"}
+
+// retryable reports whether the command may succeed when sent again. Other
+// errors, such as NOAUTH, WRONGPASS, NOPERM or WRONGTYPE, are permanent.
+func (e redisError) retryable() bool {
+	prefix, _, _ := strings.Cut(string(e), " ")
+	return slices.Contains(redisTransientErrors, prefix)
+}
+
+// readRESPReply reads a single simple, error, integer or bulk string reply
+func readRESPReply(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return "", errors.New("empty redis reply")
+	}
+
+	switch line[0] {
+	case '+', ':':
+		return line[1:], nil
+	case '-':
+		return "", redisError(line[1:])
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return "", fmt.Errorf("invalid redis bulk length: %q", line)
+		}
+		if size < 0 {
+			return "", nil
+		}
+		body := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, body); err != nil {
+			return "", err
+		}
+		return string(body[:size]), nil
+	default:
+		return "", fmt.Errorf("unexpected redis reply: %q", line)
+	}
+}
@@ -0,0 +1,234 @@
+package notification
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fakeRedisServer accepts RESP commands and records them, replying
+// with a fixed stream ID to XADD
+type fakeRedisServer struct {
+	listener net.Listener
+	password string
+	commands chan []string
+}
+
+func newFakeRedisServer(t *testing.T, password string) *fakeRedisServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	s := &fakeRedisServer{
+		listener: listener,
+		password: password,
+		commands: make(chan []string, 10),
+	}
+	go s.serve()
+	t.Cleanup(func() { listener.Close() })
+	return s
+}
+
+func (s *fakeRedisServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeRedisServer) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	authenticated := s.password == ""
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		count, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		args := make([]string, 0, count)
+		for i := 0; i < count; i++ {
+			header, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			size, _ := strconv.Atoi(strings.TrimSpace(header[1:]))
+			arg := make([]byte, size+2)
+			if _, err := io.ReadFull(reader, arg); err != nil {
+				return
+			}
+			args = append(args, string(arg[:size]))
+		}
+		s.commands <- args
+
+		switch {
+		case args[0] == "AUTH":
+			if args[len(args)-1] != s.password {
+				fmt.Fprint(conn, "-WRONGPASS invalid username-password pair\r\n")
+				continue
+			}
+			authenticated = true
+			fmt.Fprint(conn, "+OK\r\n")
+		case !authenticated:
+			fmt.Fprint(conn, "-NOAUTH Authentication required.\r\n")
+		case args[0] == "XADD":
+			fmt.Fprint(conn, "$15\r\n1700000000000-0\r\n")
+		default:
+			fmt.Fprint(conn, "+OK\r\n")
+		}
+	}
+}
+
+func TestNewRedisNotifier(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		maxLen  string
+		wantErr bool
+	}{
+		{
+			name: "valid url",
+			url:  "redis://:secret@localhost:6379/2",
+		},
+		{
+			name:    "invalid scheme",
+			url:     "http://localhost:6379",
+			wantErr: true,
+		},
+		{
+			name:    "invalid database",
+			url:     "redis://localhost/abc",
+			wantErr: true,
+		},
+		{
+			name:    "invalid maxlen",
+			url:     "redis://localhost",
+			maxLen:  "-1",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NOTIDOCK_REDIS_URL", tt.url)
+			t.Setenv("NOTIDOCK_REDIS_MAXLEN", tt.maxLen)
+
+			notifier, err := NewRedisNotifier()
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if notifier.password != "secret" || notifier.db != 2 {
+				t.Errorf("url not parsed: password=%q db=%d", notifier.password, notifier.db)
+			}
+			if notifier.stream != DefaultRedisStream || notifier.maxLen != DefaultRedisMaxLen {
+				t.Errorf("unexpected defaults: stream=%q maxLen=%d", notifier.stream, notifier.maxLen)
+			}
+		})
+	}
+
+	t.Run("not configured", func(t *testing.T) {
+		t.Setenv("NOTIDOCK_REDIS_URL", "")
+		if _, err := NewRedisNotifier(); err != ErrNotConfigured {
+			t.Errorf("expected ErrNotConfigured, got %v", err)
+		}
+	})
+}
+
+func TestRedisNotifier_Send(t *testing.T) {
+	event := Event{
+		ContainerName: "test-container",
+		Action:        "oom",
+		Time:          "2024-12-14T17:34:36Z",
+	}
+
+	t.Run("xadd with trimming", func(t *testing.T) {
+		server := newFakeRedisServer(t, "secret")
+		t.Setenv("NOTIDOCK_REDIS_URL", "redis://:secret@"+server.listener.Addr().String()+"/1")
+		t.Setenv("NOTIDOCK_REDIS_STREAM", "docker:events")
+		t.Setenv("NOTIDOCK_REDIS_MAXLEN", "500")
+
+		notifier, err := NewRedisNotifier()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := notifier.Send(context.Background(), event); err != nil {
+			t.Fatalf("failed to send notification: %v", err)
+		}
+
+		if cmd := <-server.commands; cmd[0] != "AUTH" {
+			t.Errorf("expected AUTH first, got %v", cmd)
+		}
+		if cmd := <-server.commands; cmd[0] != "SELECT" || cmd[1] != "1" {
+			t.Errorf("expected SELECT 1, got %v", cmd)
+		}
+		xadd := <-server.commands
+		wantPrefix := []string{"XADD", "docker:events", "MAXLEN", "~", "500", "*", "container", "test-container", "action", "oom", "event"}
+		if len(xadd) != len(wantPrefix)+1 {
+			t.Fatalf("unexpected XADD arguments: %v", xadd)
+		}
+		for i, want := range wantPrefix {
+			if xadd[i] != want {
+				t.Errorf("XADD arg %d = %q, want %q", i, xadd[i], want)
+			}
+		}
+		var got Event
+		if err := json.Unmarshal([]byte(xadd[len(xadd)-1]), &got); err != nil || got.Action != "oom" {
+			t.Errorf("unexpected event payload %q: %v", xadd[len(xadd)-1], err)
+		}
+	})
+
+	t.Run("server error", func(t *testing.T) {
+		server := newFakeRedisServer(t, "secret")
+		t.Setenv("NOTIDOCK_REDIS_URL", "redis://"+server.listener.Addr().String())
+
+		notifier, err := NewRedisNotifier()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		err = notifier.Send(context.Background(), event)
+		if err == nil || !strings.Contains(err.Error(), "NOAUTH") {
+			t.Fatalf("expected NOAUTH error, got %v", err)
+		}
+		if IsRetryable(err) {
+			t.Error("NOAUTH should not be retried")
+		}
+	})
+}
+
+func TestRedisError_Retryable(t *testing.T) {
+	tests := []struct {
+		reply     redisError
+		retryable bool
+	}{
+		{"NOAUTH Authentication required.", false},
+		{"WRONGPASS invalid username-password pair", false},
+		{"WRONGTYPE Operation against a key holding the wrong kind of value", false},
+		{"NOPERM this user has no permissions to run the 'xadd' command", false},
+		{"ERR unknown command 'XADD'", false},
+		{"LOADING Redis is loading the dataset in memory", true},
+		{"BUSY Redis is busy running a script", true},
+		{"READONLY You can't write against a read only replica.", true},
+	}
+	for _, tt := range tests {
+		if got := tt.reply.retryable(); got != tt.retryable {
+			t.Errorf("%q.retryable() = %v, want %v", tt.reply, got, tt.retryable)
+		}
+	}
+}
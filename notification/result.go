@@ -0,0 +1,119 @@
+package notification
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// NotifierResult is the outcome of delivering an event through one notifier
+type NotifierResult struct {
+	Notifier string
+	Err      error
+	Duration time.Duration
+	// Attempts is the number of delivery attempts, including retries.
+	// It is 0 when the event was never handed to the notifier.
+	Attempts int
+}
+
+// SendResult is the outcome of delivering an event through every notifier
+type SendResult struct {
+	Event   Event
+	Results []NotifierResult
+}
+
+// Failures returns the results of the notifiers that failed
+func (r SendResult) Failures() []NotifierResult {
+	var failures []NotifierResult
+	for _, res := range r.Results {
+		if res.Err != nil {
+			failures = append(failures, res)
+		}
+	}
+	return failures
+}
+
+// Err returns a *SendError describing the failed notifiers, or nil
+func (r SendResult) Err() error {
+	failures := r.Failures()
+	if len(failures) == 0 {
+		return nil
+	}
+	return &SendError{Failures: failures}
+}
+
+// SendError aggregates the failures of several notifiers for one event.
+// Like errors.Join, it matches any of the wrapped errors with errors.Is
+// and errors.As.
+type SendError struct {
+	Failures []NotifierResult
+}
+
+func (e *SendError) Error() string {
+	lines := make([]string, 0, len(e.Failures))
+	for _, f := range e.Failures {
+		lines = append(lines, f.Notifier+": "+f.Err.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (e *SendError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failures))
+	for _, f := range e.Failures {
+		errs = append(errs, f.Err)
+	}
+	return errs
+}
+
+// pendingSend collects the per-notifier results of one event and hands
+// the complete SendResult to done once every notifier has finished
+type pendingSend struct {
+	mu        sync.Mutex
+	remaining int
+	result    SendResult
+	done      func(SendResult)
+}
+
+func newPendingSend(event Event, notifiers int, done func(SendResult)) *pendingSend {
+	return &pendingSend{
+		remaining: notifiers,
+		result: SendResult{
+			Event:   event,
+			Results: make([]NotifierResult, 0, notifiers),
+		},
+		done: done,
+	}
+}
+
+func (p *pendingSend) record(res NotifierResult) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	p.result.Results = append(p.result.Results, res)
+	p.remaining--
+	finished := p.remaining == 0
+	p.mu.Unlock()
+
+	if finished && p.done != nil {
+		p.done(p.result)
+	}
+}
+
+type attemptsKey struct{}
+
+// withAttemptCounter returns a context through which a RetryNotifier
+// reports how many attempts a delivery took
+func withAttemptCounter(ctx context.Context) (context.Context, *atomic.Int32) {
+	counter := new(atomic.Int32)
+	return context.WithValue(ctx, attemptsKey{}, counter), counter
+}
+
+func countAttempt(ctx context.Context) {
+	if counter, ok := ctx.Value(attemptsKey{}).(*atomic.Int32); ok {
+		counter.Add(1)
+	}
+}
@@ -0,0 +1,113 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestSendError(t *testing.T) {
+	revoked := errors.New("webhook revoked")
+	err := &SendError{Failures: []NotifierResult{
+		{Notifier: "slack", Err: revoked},
+		{Notifier: "nats", Err: ErrQueueFull},
+	}}
+
+	if got, want := err.Error(), "slack: webhook revoked\nnats: notification queue full"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	if !errors.Is(err, revoked) || !errors.Is(err, ErrQueueFull) {
+		t.Error("SendError should match every wrapped error")
+	}
+	if errors.Is(err, ErrCircuitOpen) {
+		t.Error("SendError should not match errors it does not wrap")
+	}
+}
+
+func TestManager_OnResult(t *testing.T) {
+	failing := NewMockNotifier("failing")
+	failing.SetError(errors.New("connection refused"))
+	flaky := &flakyNotifier{errs: []error{errors.New("timeout")}}
+	healthy := NewMockNotifier("healthy")
+
+	retrying := WithRetry(flaky, RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond})
+	retrying.sleep = func(context.Context, time.Duration) error { return nil }
+
+	manager := NewManager(ManagerOptions{}, failing, retrying, healthy)
+	defer manager.Close(context.Background())
+
+	results := make(chan SendResult, 1)
+	manager.OnResult(func(r SendResult) { results <- r })
+
+	if err := manager.Send(context.Background(), Event{ContainerName: "test", Action: "die"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	var result SendResult
+	select {
+	case result = <-results:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the send result")
+	}
+
+	if result.Event.ContainerName != "test" {
+		t.Errorf("result for event %+v", result.Event)
+	}
+	sort.Slice(result.Results, func(i, j int) bool {
+		return result.Results[i].Notifier < result.Results[j].Notifier
+	})
+	if len(result.Results) != 3 {
+		t.Fatalf("got %d results, want 3", len(result.Results))
+	}
+
+	wantAttempts := map[string]int{"failing": 1, "flaky": 2, "healthy": 1}
+	for _, r := range result.Results {
+		if r.Attempts != wantAttempts[r.Notifier] {
+			t.Errorf("%s: %d attempts, want %d", r.Notifier, r.Attempts, wantAttempts[r.Notifier])
+		}
+	}
+
+	failures := result.Failures()
+	if len(failures) != 1 || failures[0].Notifier != "failing" {
+		t.Fatalf("unexpected failures: %+v", failures)
+	}
+	var sendErr *SendError
+	if !errors.As(result.Err(), &sendErr) || len(sendErr.Failures) != 1 {
+		t.Errorf("Err() = %v, want a SendError with one failure", result.Err())
+	}
+}
+
+func TestManager_SendReportsQueueFailures(t *testing.T) {
+	blocking := newBlockingNotifier("blocking")
+	healthy := NewMockNotifier("healthy")
+
+	manager := NewManager(ManagerOptions{
+		Queue: QueueOptions{Size: 1, Overflow: OverflowDropNewest},
+	}, blocking, healthy)
+	defer manager.Close(context.Background())
+	defer close(blocking.release)
+
+	event := Event{ContainerName: "test", Action: "die"}
+	// The first event occupies the worker and the second fills the queue
+	if err := manager.Send(context.Background(), event); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	waitFor(t, func() bool { return manager.Stats()[0].Depth == 0 })
+	if err := manager.Send(context.Background(), event); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err := manager.Send(context.Background(), event)
+	var sendErr *SendError
+	if !errors.As(err, &sendErr) {
+		t.Fatalf("Send() error = %v, want a SendError", err)
+	}
+	if len(sendErr.Failures) != 1 || sendErr.Failures[0].Notifier != "blocking" || sendErr.Failures[0].Attempts != 0 {
+		t.Errorf("unexpected failures: %+v", sendErr.Failures)
+	}
+	if !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Send() error = %v, want ErrQueueFull", err)
+	}
+}
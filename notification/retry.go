@@ -0,0 +1,204 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Default retry settings
+const (
+	DefaultRetryMaxAttempts    = 3
+	DefaultRetryInitialBackoff = 1 * time.Second
+	DefaultRetryMaxBackoff     = 30 * time.Second
+	DefaultRetryTimeout        = 2 * time.Minute
+)
+
+// DeliveryError describes a failed delivery so the retry layer can tell
+// transient failures from permanent ones
+type DeliveryError struct {
+	Err        error
+	StatusCode int
+	// RetryAfter is the delay requested by the remote service, if any
+	RetryAfter time.Duration
+	// Permanent marks failures that will not succeed when retried,
+	// such as a revoked webhook or invalid credentials
+	Permanent bool
+}
+
+func (e *DeliveryError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DeliveryError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent marks err as not worth retrying
+func Permanent(err error) error {
+	return &DeliveryError{Err: err, Permanent: true}
+}
+
+// IsRetryable reports whether a delivery that failed with err may succeed
+// when attempted again. Errors that are not a DeliveryError are treated as
+// transient, since they are usually network failures.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var deliveryErr *DeliveryError
+	if errors.As(err, &deliveryErr) {
+		return !deliveryErr.Permanent
+	}
+	return true
+}
+
+// newHTTPError builds a DeliveryError for an unsuccessful HTTP response.
+// Client errors are permanent, except for timeouts and rate limiting.
+func newHTTPError(resp *http.Response, err error) error {
+	deliveryErr := &DeliveryError{
+		Err:        err,
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+	switch {
+	case resp.StatusCode == http.StatusRequestTimeout,
+		resp.StatusCode == http.StatusTooEarly,
+		resp.StatusCode == http.StatusTooManyRequests:
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		deliveryErr.Permanent = true
+	}
+	return deliveryErr
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds
+// or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// RetryPolicy configures how failed deliveries are retried
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Timeout bounds the total time spent on one event, including
+	// every attempt and the delays between them
+	Timeout time.Duration
+}
+
+// RetryNotifier wraps a Notifier and retries failed deliveries with
+// jittered exponential backoff
+type RetryNotifier struct {
+	notifier Notifier
+	policy   RetryPolicy
+	sleep    func(ctx context.Context, d time.Duration) error
+}
+
+// WithRetry wraps n so that retryable failures are attempted again
+// according to policy
+func WithRetry(n Notifier, policy RetryPolicy) *RetryNotifier {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = DefaultRetryMaxAttempts
+	}
+	if policy.InitialBackoff <= 0 {
+		policy.InitialBackoff = DefaultRetryInitialBackoff
+	}
+	if policy.MaxBackoff < policy.InitialBackoff {
+		policy.MaxBackoff = policy.InitialBackoff
+	}
+	return &RetryNotifier{
+		notifier: n,
+		policy:   policy,
+		sleep:    sleepContext,
+	}
+}
+
+func (r *RetryNotifier) Name() string {
+	return r.notifier.Name()
+}
+
+// Unwrap returns the wrapped notifier
+func (r *RetryNotifier) Unwrap() Notifier {
+	return r.notifier
+}
+
+// Send implements the Notifier interface, retrying the wrapped notifier
+func (r *RetryNotifier) Send(ctx context.Context, event Event) error {
+	if r.policy.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.policy.Timeout)
+		defer cancel()
+	}
+
+	for attempt := 1; ; attempt++ {
+		countAttempt(ctx)
+		err := r.notifier.Send(ctx, event)
+		if err == nil {
+			return nil
+		}
+		if attempt >= r.policy.MaxAttempts || !IsRetryable(err) || ctx.Err() != nil {
+			return fmt.Errorf("giving up after %d attempt(s): %w", attempt, err)
+		}
+
+		delay := r.backoff(attempt)
+		var deliveryErr *DeliveryError
+		if errors.As(err, &deliveryErr) && deliveryErr.RetryAfter > 0 {
+			delay = deliveryErr.RetryAfter
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+			return fmt.Errorf("giving up after %d attempt(s), next retry in %s would exceed the deadline: %w", attempt, delay, err)
+		}
+
+		slog.Warn("notification failed, retrying",
+			"notifier", r.notifier.Name(),
+			"containerName", event.ContainerName,
+			"action", event.Action,
+			"attempt", attempt,
+			"retryIn", delay,
+			"error", err,
+		)
+
+		if err := r.sleep(ctx, delay); err != nil {
+			return fmt.Errorf("giving up after %d attempt(s): %w", attempt, err)
+		}
+	}
+}
+
+// backoff returns the delay before the next attempt: the exponential
+// backoff for the attempt, with the upper half randomised
+func (r *RetryNotifier) backoff(attempt int) time.Duration {
+	d := r.policy.InitialBackoff << (attempt - 1)
+	if d <= 0 || d > r.policy.MaxBackoff {
+		d = r.policy.MaxBackoff
+	}
+	half := d / 2
+	return half + rand.N(half+1)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,201 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyNotifier fails with the queued errors before succeeding
+type flakyNotifier struct {
+	mu       sync.Mutex
+	errs     []error
+	attempts int
+}
+
+func (f *flakyNotifier) Send(ctx context.Context, event Event) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.attempts++
+	if len(f.errs) == 0 {
+		return nil
+	}
+	err := f.errs[0]
+	f.errs = f.errs[1:]
+	return err
+}
+
+func (f *flakyNotifier) Name() string {
+	return "flaky"
+}
+
+func newTestRetryNotifier(n Notifier, policy RetryPolicy) (*RetryNotifier, *[]time.Duration) {
+	r := WithRetry(n, policy)
+	var delays []time.Duration
+	r.sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return ctx.Err()
+	}
+	return r, &delays
+}
+
+func TestRetryNotifier_Send(t *testing.T) {
+	transient := errors.New("connection reset")
+	rateLimited := &DeliveryError{Err: errors.New("rate limited"), StatusCode: 429, RetryAfter: 7 * time.Second}
+	revoked := Permanent(errors.New("webhook revoked"))
+
+	tests := []struct {
+		name         string
+		errs         []error
+		maxAttempts  int
+		wantErr      bool
+		wantAttempts int
+	}{
+		{
+			name:         "succeeds first time",
+			maxAttempts:  3,
+			wantAttempts: 1,
+		},
+		{
+			name:         "recovers from transient errors",
+			errs:         []error{transient, transient},
+			maxAttempts:  3,
+			wantAttempts: 3,
+		},
+		{
+			name:         "gives up after max attempts",
+			errs:         []error{transient, transient, transient},
+			maxAttempts:  3,
+			wantErr:      true,
+			wantAttempts: 3,
+		},
+		{
+			name:         "does not retry permanent errors",
+			errs:         []error{revoked},
+			maxAttempts:  3,
+			wantErr:      true,
+			wantAttempts: 1,
+		},
+		{
+			name:         "retries rate limited requests",
+			errs:         []error{rateLimited},
+			maxAttempts:  3,
+			wantAttempts: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flaky := &flakyNotifier{errs: tt.errs}
+			r, _ := newTestRetryNotifier(flaky, RetryPolicy{MaxAttempts: tt.maxAttempts})
+
+			err := r.Send(context.Background(), Event{ContainerName: "test", Action: "die"})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if flaky.attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", flaky.attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestRetryNotifier_Backoff(t *testing.T) {
+	transient := errors.New("connection reset")
+	flaky := &flakyNotifier{errs: []error{transient, transient, transient, transient}}
+	r, delays := newTestRetryNotifier(flaky, RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     300 * time.Millisecond,
+	})
+
+	if err := r.Send(context.Background(), Event{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	caps := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond}
+	if len(*delays) != len(caps) {
+		t.Fatalf("got %d delays, want %d", len(*delays), len(caps))
+	}
+	for i, d := range *delays {
+		if d < caps[i]/2 || d > caps[i] {
+			t.Errorf("delay %d = %s, want between %s and %s", i, d, caps[i]/2, caps[i])
+		}
+	}
+}
+
+func TestRetryNotifier_RetryAfter(t *testing.T) {
+	flaky := &flakyNotifier{errs: []error{
+		&DeliveryError{Err: errors.New("rate limited"), StatusCode: 429, RetryAfter: 7 * time.Second},
+	}}
+	r, delays := newTestRetryNotifier(flaky, RetryPolicy{MaxAttempts: 3})
+
+	if err := r.Send(context.Background(), Event{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*delays) != 1 || (*delays)[0] != 7*time.Second {
+		t.Errorf("delays = %v, want [7s]", *delays)
+	}
+}
+
+func TestRetryNotifier_Timeout(t *testing.T) {
+	flaky := &flakyNotifier{errs: []error{
+		&DeliveryError{Err: errors.New("rate limited"), StatusCode: 429, RetryAfter: time.Minute},
+	}}
+	r, delays := newTestRetryNotifier(flaky, RetryPolicy{MaxAttempts: 3, Timeout: 10 * time.Second})
+
+	if err := r.Send(context.Background(), Event{}); err == nil {
+		t.Error("expected error when Retry-After exceeds the deadline")
+	}
+	if len(*delays) != 0 || flaky.attempts != 1 {
+		t.Errorf("expected a single attempt without waiting, got %d attempts and delays %v", flaky.attempts, *delays)
+	}
+}
+
+func TestSlackNotifier_SendErrorClassification(t *testing.T) {
+	tests := []struct {
+		name           string
+		status         int
+		retryAfter     string
+		wantRetryable  bool
+		wantRetryAfter time.Duration
+	}{
+		{"server error", http.StatusBadGateway, "", true, 0},
+		{"rate limited", http.StatusTooManyRequests, "30", true, 30 * time.Second},
+		{"webhook revoked", http.StatusNotFound, "", false, 0},
+		{"invalid payload", http.StatusBadRequest, "", false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			notifier := &SlackNotifier{
+				webhookURL: server.URL,
+				client:     server.Client(),
+			}
+
+			err := notifier.Send(context.Background(), Event{ContainerName: "test", Action: "die"})
+			if got := IsRetryable(err); got != tt.wantRetryable {
+				t.Errorf("IsRetryable() = %v, want %v", got, tt.wantRetryable)
+			}
+			var deliveryErr *DeliveryError
+			if !errors.As(err, &deliveryErr) {
+				t.Fatalf("expected DeliveryError, got %T", err)
+			}
+			if deliveryErr.StatusCode != tt.status || deliveryErr.RetryAfter != tt.wantRetryAfter {
+				t.Errorf("got status %d retryAfter %s", deliveryErr.StatusCode, deliveryErr.RetryAfter)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package notification
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Severity is a coarse classification of how urgent an event is
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+// EventSeverity classifies an event: OOM kills, crash loops and unhealthy
+// containers are critical, non-zero exits and flapping health are warnings
+func EventSeverity(event Event) Severity {
+	switch {
+	case isCriticalFailure(event):
+		return SeverityCritical
+	case event.ExitCode != "" && event.Labels["exitCode"] != "0", event.Action == "health_flapping":
+		return SeverityWarning
+	}
+	return SeverityInfo
+}
+
+// Route sends the events it matches to a set of notifiers. Routes are
+// evaluated in order and, like Alertmanager, evaluation stops at the first
+// matching route unless it sets Continue.
+type Route struct {
+	Name  string
+	Match RouteMatch
+	// Notifiers are the names of the notifiers matching events go to. A
+	// route without notifiers silences the events it matches.
+	Notifiers []string
+	Continue  bool
+}
+
+// RouteMatch selects events. Every non-empty field must match, and a field
+// matches when any of its values does. Names, images, projects and label
+// values are glob patterns as understood by path.Match, except that * and ?
+// match / as well, so "ghcr.io/acme/*" matches nested repositories.
+type RouteMatch struct {
+	Containers      []string
+	Images          []string
+	ComposeProjects []string
+	// Labels maps label names to value patterns; use "*" to only require
+	// that the label is present
+	Labels     map[string]string
+	Actions    []string
+	ExitCodes  []string
+	Severities []Severity
+}
+
+const composeProjectLabel = "com.docker.compose.project"
+
+func (m RouteMatch) matches(event Event) bool {
+	if len(m.Containers) > 0 && !matchAny(m.Containers, event.ContainerName) {
+		return false
+	}
+	if len(m.Images) > 0 && !matchLabel(m.Images, event.Labels, "image") {
+		return false
+	}
+	if len(m.ComposeProjects) > 0 && !matchLabel(m.ComposeProjects, event.Labels, composeProjectLabel) {
+		return false
+	}
+	for name, pattern := range m.Labels {
+		if !matchLabel([]string{pattern}, event.Labels, name) {
+			return false
+		}
+	}
+	if len(m.Actions) > 0 && !slices.Contains(m.Actions, event.Action) {
+		return false
+	}
+	if len(m.ExitCodes) > 0 && !matchLabel(m.ExitCodes, event.Labels, "exitCode") {
+		return false
+	}
+	if len(m.Severities) > 0 && !slices.Contains(m.Severities, EventSeverity(event)) {
+		return false
+	}
+	return true
+}
+
+func (m RouteMatch) validate() error {
+	patterns := slices.Concat(m.Containers, m.Images, m.ComposeProjects, m.ExitCodes)
+	for _, pattern := range m.Labels {
+		patterns = append(patterns, pattern)
+	}
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	for _, s := range m.Severities {
+		switch s {
+		case SeverityInfo, SeverityWarning, SeverityCritical:
+		default:
+			return fmt.Errorf("unknown severity %q", s)
+		}
+	}
+	return nil
+}
+
+func matchLabel(patterns []string, labels map[string]string, name string) bool {
+	value, ok := labels[name]
+	return ok && matchAny(patterns, value)
+}
+
+func matchAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if globMatch(pattern, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// globMatch reports whether value matches the pattern, with wildcards
+// matching / as well. path.Match never matches / with a wildcard, so it
+// is swapped for a byte that names, images and labels do not contain.
+func globMatch(pattern, value string) bool {
+	ok, _ := path.Match(strings.ReplaceAll(pattern, "/", "\x00"), strings.ReplaceAll(value, "/", "\x00"))
+	return ok
+}
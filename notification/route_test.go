@@ -0,0 +1,203 @@
+package notification
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestRouteMatch(t *testing.T) {
+	event := Event{
+		ContainerName: "payments-api",
+		Action:        "die",
+		ExitCode:      "137 (SIGKILL)",
+		Labels: map[string]string{
+			"image":              "ghcr.io/acme/payments:1.4",
+			composeProjectLabel:  "prod",
+			"team":               "payments",
+			"exitCode":           "137",
+			"com.example.region": "eu-west-1",
+		},
+	}
+
+	tests := []struct {
+		name  string
+		match RouteMatch
+		want  bool
+	}{
+		{"empty match", RouteMatch{}, true},
+		{"container glob", RouteMatch{Containers: []string{"payments-*"}}, true},
+		{"container mismatch", RouteMatch{Containers: []string{"billing-*"}}, false},
+		{"image glob", RouteMatch{Images: []string{"ghcr.io/acme/*"}}, true},
+		{"compose project", RouteMatch{ComposeProjects: []string{"staging", "prod"}}, true},
+		{"compose project mismatch", RouteMatch{ComposeProjects: []string{"staging"}}, false},
+		{"label selector", RouteMatch{Labels: map[string]string{"team": "payments", "com.example.region": "eu-*"}}, true},
+		{"label present", RouteMatch{Labels: map[string]string{"team": "*"}}, true},
+		{"missing label", RouteMatch{Labels: map[string]string{"tier": "*"}}, false},
+		{"action", RouteMatch{Actions: []string{"oom", "die"}}, true},
+		{"action mismatch", RouteMatch{Actions: []string{"start"}}, false},
+		{"exit code", RouteMatch{ExitCodes: []string{"1", "137"}}, true},
+		{"severity", RouteMatch{Severities: []Severity{SeverityWarning}}, true},
+		{"severity mismatch", RouteMatch{Severities: []Severity{SeverityInfo}}, false},
+		{"all fields must match", RouteMatch{Actions: []string{"die"}, ComposeProjects: []string{"staging"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.match.matches(event); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouteMatch_NestedPaths(t *testing.T) {
+	tests := []struct {
+		pattern string
+		image   string
+		want    bool
+	}{
+		{"ghcr.io/acme/*", "ghcr.io/acme/team/api:2", true},
+		{"*/postgres:*", "docker.io/library/postgres:16", true},
+		{"*/library/*", "docker.io/library/redis:7", true},
+		{"ghcr.io/acme/*", "ghcr.io/other/api:2", false},
+		{"docker.io/?ibrary/*", "docker.io/library/redis:7", true},
+		{"*/postgres:*", "docker.io/library/postgres-exporter:1", false},
+	}
+
+	for _, tt := range tests {
+		event := Event{Labels: map[string]string{"image": tt.image}}
+		match := RouteMatch{Images: []string{tt.pattern}}
+		if got := match.matches(event); got != tt.want {
+			t.Errorf("%q matches %q = %v, want %v", tt.pattern, tt.image, got, tt.want)
+		}
+	}
+}
+
+func TestEventSeverity(t *testing.T) {
+	tests := []struct {
+		name  string
+		event Event
+		want  Severity
+	}{
+		{"oom", Event{Action: "oom"}, SeverityCritical},
+		{"killed", Event{Action: "die", ExitCode: "137", Labels: map[string]string{"exitCode": "137"}}, SeverityWarning},
+		{"unhealthy", Event{Action: "health_status", Labels: map[string]string{"health_status": "unhealthy"}}, SeverityCritical},
+		{"non-zero exit", Event{Action: "die", ExitCode: "1", Labels: map[string]string{"exitCode": "1"}}, SeverityWarning},
+		{"clean exit", Event{Action: "die", ExitCode: "0", Labels: map[string]string{"exitCode": "0"}}, SeverityInfo},
+		{"flapping", Event{Action: "health_flapping", Labels: map[string]string{"health_status": "unhealthy"}}, SeverityWarning},
+		{"stopped flapping unhealthy", Event{Action: "health_flapping_stopped", Labels: map[string]string{"health_status": "unhealthy"}}, SeverityCritical},
+		{"start", Event{Action: "start"}, SeverityInfo},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EventSeverity(tt.event); got != tt.want {
+				t.Errorf("EventSeverity() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManager_Routes(t *testing.T) {
+	pagerduty := NewMockNotifier("pagerduty")
+	slack := NewMockNotifier("slack")
+	file := NewMockNotifier("file")
+
+	manager := NewManager(ManagerOptions{}, pagerduty, slack, file)
+	err := manager.SetRoutes([]Route{
+		{Match: RouteMatch{Severities: []Severity{SeverityCritical, SeverityWarning}}, Notifiers: []string{"pagerduty"}, Continue: true},
+		{Match: RouteMatch{Actions: []string{"create"}}},
+		{Match: RouteMatch{ComposeProjects: []string{"prod"}}, Notifiers: []string{"slack", "file"}},
+		{Notifiers: []string{"file"}},
+	})
+	if err != nil {
+		t.Fatalf("SetRoutes() error = %v", err)
+	}
+
+	events := []Event{
+		{ContainerName: "api", Action: "oom", Labels: map[string]string{composeProjectLabel: "prod"}},
+		{ContainerName: "worker", Action: "die", ExitCode: "1", Labels: map[string]string{"exitCode": "1"}},
+		{ContainerName: "api", Action: "start", Labels: map[string]string{composeProjectLabel: "prod"}},
+		{ContainerName: "api", Action: "create", Labels: map[string]string{composeProjectLabel: "prod"}},
+	}
+	for _, e := range events {
+		if err := manager.Send(context.Background(), e); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+	if err := manager.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	received := func(n *MockNotifier) []string {
+		var got []string
+		for _, e := range n.GetEvents() {
+			got = append(got, e.ContainerName+"/"+e.Action)
+		}
+		return got
+	}
+	assertEvents(t, "pagerduty", received(pagerduty), "api/oom", "worker/die")
+	assertEvents(t, "slack", received(slack), "api/oom", "api/start")
+	assertEvents(t, "file", received(file), "api/oom", "worker/die", "api/start")
+}
+
+func TestManager_TargetOverridesRoutes(t *testing.T) {
+	slack := NewMockNotifier("slack")
+	webhook := NewMockNotifier("webhook")
+
+	manager := NewManager(ManagerOptions{}, slack, webhook)
+	if err := manager.SetRoutes([]Route{{Notifiers: []string{"slack"}}}); err != nil {
+		t.Fatalf("SetRoutes() error = %v", err)
+	}
+
+	events := []Event{
+		{ContainerName: "payments", Action: "die", Target: &Target{Notifiers: []string{"webhook"}}},
+		{ContainerName: "unknown", Action: "die", Target: &Target{Notifiers: []string{"pagerduty"}}},
+		{ContainerName: "web", Action: "die"},
+	}
+	for _, e := range events {
+		manager.Send(context.Background(), e)
+	}
+	manager.Close(context.Background())
+
+	received := func(n *MockNotifier) []string {
+		var got []string
+		for _, e := range n.GetEvents() {
+			got = append(got, e.ContainerName)
+		}
+		return got
+	}
+	assertEvents(t, "webhook", received(webhook), "payments")
+	assertEvents(t, "slack", received(slack), "unknown", "web")
+}
+
+func TestManager_SetRoutesValidation(t *testing.T) {
+	manager := NewManager(ManagerOptions{}, NewMockNotifier("slack"))
+	defer manager.Close(context.Background())
+
+	tests := []struct {
+		name  string
+		route Route
+	}{
+		{"unknown notifier", Route{Notifiers: []string{"pagerduty"}}},
+		{"invalid pattern", Route{Match: RouteMatch{Containers: []string{"api-["}}, Notifiers: []string{"slack"}}},
+		{"invalid exit code pattern", Route{Match: RouteMatch{ExitCodes: []string{"13[7"}}, Notifiers: []string{"slack"}}},
+		{"unknown severity", Route{Match: RouteMatch{Severities: []Severity{"urgent"}}, Notifiers: []string{"slack"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := manager.SetRoutes([]Route{tt.route}); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func assertEvents(t *testing.T, notifier string, got []string, want ...string) {
+	t.Helper()
+	if !slices.Equal(got, want) {
+		t.Errorf("%s received %v, want %v", notifier, got, want)
+	}
+}
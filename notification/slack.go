@@ -6,24 +6,45 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"net/url"
-	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type SlackNotifier struct {
-	webhookURL string
-	client     *http.Client
+	name            string
+	webhookURL      string
+	dateTokens      bool
+	allowedChannels []string
+	client          *http.Client
+}
+
+// SlackOptions configures a Slack notifier
+type SlackOptions struct {
+	// Name identifies the notifier in routes and logs, "slack" by default
+	Name       string
+	WebhookURL string
+	// DateTokens shows times with Slack date formatting, so every reader
+	// sees them in their own time zone
+	DateTokens bool
+	// AllowedChannels are the channels containers may send their
+	// notifications to with a label
+	AllowedChannels []string
 }
 
 type slackMessage struct {
+	Channel     string       `json:"channel,omitempty"`
 	Text        string       `json:"text"`
 	Attachments []attachment `json:"attachments,omitempty"`
 }
 
 type attachment struct {
-	Color  string  `json:"color"`
-	Fields []field `json:"fields"`
+	Fallback string  `json:"fallback,omitempty"`
+	Color    string  `json:"color"`
+	Fields   []field `json:"fields"`
 }
 
 type field struct {
@@ -34,12 +55,10 @@ type field struct {
 
 // getIcon returns an appropriate emoji based on the action
 func getIcon(action string, exitCode string, labels map[string]string) string {
-	// First check for specific exit codes that might override the action icon
-	if exitCode != "" {
-		// Check for OOM kill
-		if exitCode == "137" {
-			return ":warning: :memory:"
-		}
+	// First check for specific exit codes that might override the action icon.
+	// Exit code 137 is any SIGKILL, such as docker kill, so only the oom
+	// action shows the memory icon.
+	if exitCode != "" && action != "oom" {
 		// Check for error exit codes
 		if exitCode != "0" {
 			return ":x:"
@@ -89,6 +108,24 @@ func getIcon(action string, exitCode string, labels map[string]string) string {
 		return ":arrow_forward: :terminal:"
 	case "exec_die":
 		return ":x: :terminal:"
+	case "notifier_down":
+		return ":rotating_light:"
+	case "notifier_recovered":
+		return ":white_check_mark:"
+	case "startup_inventory":
+		return ":clipboard:"
+	case "crash_loop":
+		return ":repeat: :rotating_light:"
+	case "crash_loop_resolved":
+		return ":white_check_mark:"
+	case "health_flapping":
+		return ":warning:"
+	case "health_flapping_stopped":
+		return ":heavy_check_mark:"
+	case "throttled":
+		return ":mute:"
+	case "throttle_summary":
+		return ":loud_sound:"
 	default:
 		return ":information_source:"
 	}
@@ -122,36 +159,129 @@ func getColor(action string, labels map[string]string) string {
 		return "#1E90FF" // blue
 	case "exec_create", "exec_start":
 		return "#36a64f" // green
-	case "exec_die":
+	case "exec_die", "notifier_down", "crash_loop":
 		return "#ff0000" // red
+	case "notifier_recovered", "crash_loop_resolved":
+		return "#36a64f" // green
+	case "health_flapping", "throttled":
+		return "#FFA500" // orange
+	case "health_flapping_stopped":
+		if labels["health_status"] == "unhealthy" {
+			return "#ff0000" // red
+		}
+		return "#36a64f" // green
+	case "startup_inventory":
+		if labels["unhealthy"] != "" || labels["exited"] != "" {
+			return "#FFA500" // orange
+		}
+		return "#36a64f" // green
 	default:
 		return "#808080" // grey
 	}
 }
 
+// formatHealthLog lists health check results as a code block, most recent
+// last
+func formatHealthLog(probes []HealthProbe) string {
+	now := time.Now()
+	var b strings.Builder
+	b.WriteString("```")
+	for i, p := range probes {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%s, exit %d: %s", timeAgo(p.End, now), p.ExitCode, p.Output)
+	}
+	b.WriteString("```")
+	return b.String()
+}
+
+// channel returns the channel the event asks for, or "" for the webhook's
+// default channel when it is not allowed. Webhooks only honour it when they
+// are allowed to post to other channels.
+func (s *SlackNotifier) channel(event Event) string {
+	if event.Target == nil || event.Target.SlackChannel == "" {
+		return ""
+	}
+	requested := normalizeSlackChannel(event.Target.SlackChannel)
+	for _, allowed := range s.allowedChannels {
+		if requested == allowed {
+			return event.Target.SlackChannel
+		}
+	}
+	slog.Warn("ignoring slack channel requested by container",
+		"containerName", event.ContainerName,
+		"channel", event.Target.SlackChannel,
+	)
+	return ""
+}
+
+// normalizeSlackChannel makes "#Alerts" and "alerts" compare equal
+func normalizeSlackChannel(channel string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(channel), "#"))
+}
+
+// formatTime returns the event time, as a Slack date token when enabled.
+// The formatted time is the fallback for clients that cannot show it.
+func (s *SlackNotifier) formatTime(event Event) string {
+	if !s.dateTokens || event.Timestamp.IsZero() {
+		return event.Time
+	}
+	return fmt.Sprintf("<!date^%d^{date_short_pretty} {time_secs}|%s>", event.Timestamp.Unix(), event.Time)
+}
+
 func NewSlackNotifier() (*SlackNotifier, error) {
-	webhookURL := os.Getenv("NOTIDOCK_SLACK_WEBHOOK_URL")
-	if webhookURL == "" {
-		return nil, fmt.Errorf("NOTIDOCK_SLACK_WEBHOOK_URL environment variable is not set")
+	var env envVars
+	opts := SlackOptions{
+		WebhookURL:      env.get("NOTIDOCK_SLACK_WEBHOOK_URL"),
+		AllowedChannels: splitList(env.get("NOTIDOCK_SLACK_ALLOWED_CHANNELS")),
+	}
+	dateTokens := env.get("NOTIDOCK_SLACK_DATE_TOKENS")
+	if env.err != nil {
+		return nil, env.err
+	}
+	if opts.WebhookURL == "" {
+		return nil, fmt.Errorf("%w: NOTIDOCK_SLACK_WEBHOOK_URL environment variable is not set", ErrNotConfigured)
+	}
+	if dateTokens != "" {
+		var err error
+		if opts.DateTokens, err = strconv.ParseBool(dateTokens); err != nil {
+			return nil, fmt.Errorf("invalid NOTIDOCK_SLACK_DATE_TOKENS %q: must be true or false", dateTokens)
+		}
 	}
+	return NewSlackNotifierWithOptions(opts)
+}
 
-	parsedURL, err := url.Parse(webhookURL)
+// NewSlackNotifierWithOptions creates a Slack notifier from explicit settings
+func NewSlackNotifierWithOptions(opts SlackOptions) (*SlackNotifier, error) {
+	parsedURL, err := url.Parse(opts.WebhookURL)
 	if err != nil || parsedURL.Scheme != "https" {
 		return nil, errors.New("invalid webhook URL: must be a valid URL and use https")
 	}
+	if opts.Name == "" {
+		opts.Name = "slack"
+	}
+	allowedChannels := make([]string, 0, len(opts.AllowedChannels))
+	for _, channel := range opts.AllowedChannels {
+		allowedChannels = append(allowedChannels, normalizeSlackChannel(channel))
+	}
 
 	return &SlackNotifier{
-		webhookURL: webhookURL,
-		client:     &http.Client{},
+		name:            opts.Name,
+		webhookURL:      opts.WebhookURL,
+		dateTokens:      opts.DateTokens,
+		allowedChannels: allowedChannels,
+		client:          &http.Client{},
 	}, nil
 }
 
 func (s *SlackNotifier) Name() string {
-	return "slack"
+	return s.name
 }
 
 // Send implements the Notifier interface for Slack
 func (s *SlackNotifier) Send(ctx context.Context, event Event) error {
+	event = withMessage(event)
 	fields := []field{
 		{
 			Title: "Action",
@@ -160,7 +290,7 @@ func (s *SlackNotifier) Send(ctx context.Context, event Event) error {
 		},
 		{
 			Title: "Time",
-			Value: event.Time,
+			Value: s.formatTime(event),
 			Short: true,
 		},
 	}
@@ -181,6 +311,20 @@ func (s *SlackNotifier) Send(ctx context.Context, event Event) error {
 				Short: true,
 			})
 		}
+		if image, ok := event.Labels["image"]; ok {
+			fields = append(fields, field{
+				Title: "Image",
+				Value: image,
+				Short: true,
+			})
+		}
+		if len(event.HealthLog) > 0 {
+			fields = append(fields, field{
+				Title: "Last Health Checks",
+				Value: formatHealthLog(event.HealthLog),
+				Short: false,
+			})
+		}
 	} else {
 		// Regular event handling
 		// Add image information if available
@@ -193,7 +337,7 @@ func (s *SlackNotifier) Send(ctx context.Context, event Event) error {
 		}
 
 		// Add execution duration if available
-		if event.ExecDuration != "N/A" {
+		if event.ExecDuration != "" && event.ExecDuration != "N/A" {
 			fields = append(fields, field{
 				Title: "Duration",
 				Value: event.ExecDuration,
@@ -211,29 +355,33 @@ func (s *SlackNotifier) Send(ctx context.Context, event Event) error {
 		}
 	}
 
-	// Add remaining labels that haven't been explicitly handled
-	for k, v := range event.Labels {
-		// Skip labels we've already handled
-		if k == "image" || k == "exitCode" || k == "execDuration" ||
-			(event.Action == "health_status" && (k == "health_status" || k == "failing_streak")) {
-			continue
+	// Add remaining labels that haven't been explicitly handled. Health
+	// events carry the container's labels for routing only.
+	if event.Action != "health_status" {
+		for k, v := range event.Labels {
+			// Skip labels we've already handled
+			if k == "image" || k == "exitCode" || k == "execDuration" {
+				continue
+			}
+			fields = append(fields, field{
+				Title: k,
+				Value: v,
+				Short: true,
+			})
 		}
-		fields = append(fields, field{
-			Title: k,
-			Value: v,
-			Short: true,
-		})
 	}
 
 	icon := getIcon(event.Action, event.Labels["exitCode"], event.Labels)
 	color := getColor(event.Action, event.Labels)
 
 	msg := slackMessage{
-		Text: fmt.Sprintf("%s Container Event: %s", icon, event.ContainerName),
+		Channel: s.channel(event),
+		Text:    fmt.Sprintf("%s %s", icon, event.Title),
 		Attachments: []attachment{
 			{
-				Color:  color,
-				Fields: fields,
+				Fallback: event.Summary,
+				Color:    color,
+				Fields:   fields,
 			},
 		},
 	}
@@ -251,12 +399,12 @@ func (s *SlackNotifier) Send(ctx context.Context, event Event) error {
 
 	resp, err := s.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send slack notification: %w", err)
+		return fmt.Errorf("failed to send slack notification: %w", redactURLError(err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("slack notification failed with status code: %d", resp.StatusCode)
+		return newHTTPError(resp, fmt.Errorf("slack notification failed with status code: %d", resp.StatusCode))
 	}
 
 	return nil
@@ -6,6 +6,8 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -55,6 +57,120 @@ func TestNewSlackNotifier(t *testing.T) {
 	}
 }
 
+func TestNewSlackNotifier_SecretFile(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "slack_webhook")
+	if err := os.WriteFile(secret, []byte("https://hooks.slack.com/services/xxx/yyy/zzz\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("NOTIDOCK_SLACK_WEBHOOK_URL", "")
+	t.Setenv("NOTIDOCK_SLACK_WEBHOOK_URL_FILE", secret)
+
+	notifier, err := NewSlackNotifier()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if notifier.webhookURL != "https://hooks.slack.com/services/xxx/yyy/zzz" {
+		t.Errorf("webhookURL = %q", notifier.webhookURL)
+	}
+}
+
+func TestSlackNotifier_SendErrorHidesWebhook(t *testing.T) {
+	notifier, err := NewSlackNotifierWithOptions(SlackOptions{WebhookURL: "https://127.0.0.1:1/services/xxx/yyy/zzz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = notifier.Send(context.Background(), Event{ContainerName: "web", Action: "die"})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if strings.Contains(err.Error(), "xxx/yyy/zzz") {
+		t.Errorf("error %q contains the webhook secret", err)
+	}
+}
+
+func TestFormatHealthLog(t *testing.T) {
+	now := time.Now()
+	got := formatHealthLog([]HealthProbe{
+		{End: now.Add(-2 * time.Minute), ExitCode: 1, Output: "curl: (7) Failed to connect"},
+		{End: now, ExitCode: 1, Output: "timeout"},
+	})
+	want := "```2m ago, exit 1: curl: (7) Failed to connect\njust now, exit 1: timeout```"
+	if got != want {
+		t.Errorf("formatHealthLog() = %q, want %q", got, want)
+	}
+}
+
+func TestSlackNotifier_FormatTime(t *testing.T) {
+	event := Event{
+		Time:      "2024-12-14T17:34:36Z",
+		Timestamp: time.Date(2024, 12, 14, 17, 34, 36, 0, time.UTC),
+	}
+
+	plain := &SlackNotifier{}
+	if got := plain.formatTime(event); got != event.Time {
+		t.Errorf("formatTime() = %q, want %q", got, event.Time)
+	}
+
+	tokens := &SlackNotifier{dateTokens: true}
+	want := "<!date^1734197676^{date_short_pretty} {time_secs}|2024-12-14T17:34:36Z>"
+	if got := tokens.formatTime(event); got != want {
+		t.Errorf("formatTime() = %q, want %q", got, want)
+	}
+	if got := tokens.formatTime(Event{Time: "yesterday"}); got != "yesterday" {
+		t.Errorf("formatTime() without timestamp = %q, want the formatted time", got)
+	}
+}
+
+func TestSlackNotifier_Channel(t *testing.T) {
+	notifier, err := NewSlackNotifierWithOptions(SlackOptions{
+		WebhookURL:      "https://hooks.slack.com/services/a",
+		AllowedChannels: []string{"#team-payments", "Team-Search"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		target *Target
+		want   string
+	}{
+		{"no target", nil, ""},
+		{"allowed", &Target{SlackChannel: "#team-payments"}, "#team-payments"},
+		{"allowed without hash", &Target{SlackChannel: "team-search"}, "team-search"},
+		{"not allowed", &Target{SlackChannel: "#general"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := notifier.channel(Event{ContainerName: "test", Target: tt.target}); got != tt.want {
+				t.Errorf("channel() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSlackNotifierWithOptions(t *testing.T) {
+	tests := []struct {
+		opts     SlackOptions
+		wantName string
+	}{
+		{SlackOptions{WebhookURL: "https://hooks.slack.com/services/a"}, "slack"},
+		{SlackOptions{Name: "alerts", WebhookURL: "https://hooks.slack.com/services/b"}, "alerts"},
+	}
+
+	for _, tt := range tests {
+		notifier, err := NewSlackNotifierWithOptions(tt.opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if notifier.Name() != tt.wantName {
+			t.Errorf("Name() = %q, want %q", notifier.Name(), tt.wantName)
+		}
+	}
+}
+
 func TestSlackNotifier_Send(t *testing.T) {
 	// Create a test server to mock Slack's webhook endpoint
 	var receivedBody string
@@ -112,8 +228,8 @@ func TestSlackNotifier_Send(t *testing.T) {
 		}
 	}
 
-	// Verify the message format with icon (for OOM kill)
-	expectedText := fmt.Sprintf(`"text":":warning: :memory: Container Event: test-container"`)
+	// Verify the message format with icon (for a non-zero exit)
+	expectedText := fmt.Sprintf(`"text":":x: Container Event: test-container"`)
 	if !strings.Contains(receivedBody, expectedText) {
 		t.Errorf("payload doesn't contain expected text field with icon.\nExpected: %s\nGot: %s", expectedText, receivedBody)
 	}
@@ -123,6 +239,45 @@ func TestSlackNotifier_Send(t *testing.T) {
 	}
 }
 
+func TestSlackNotifier_SendHealthStatus(t *testing.T) {
+	var receivedBody string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		receivedBody = string(body)
+	}))
+	defer server.Close()
+
+	notifier := &SlackNotifier{
+		webhookURL: server.URL,
+		client:     server.Client(),
+	}
+	err := notifier.Send(context.Background(), Event{
+		ContainerName: "api",
+		Action:        "health_status",
+		Labels: map[string]string{
+			"health_status":              "unhealthy",
+			"failing_streak":             "3",
+			"image":                      "api:1.2",
+			"com.docker.compose.project": "shop",
+			"maintainer":                 "team@example.com",
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to send notification: %v", err)
+	}
+
+	for _, want := range []string{"Health Status", "unhealthy", "Failing Streak", "api:1.2"} {
+		if !strings.Contains(receivedBody, want) {
+			t.Errorf("expected payload to contain %q.\nPayload: %s", want, receivedBody)
+		}
+	}
+	for _, label := range []string{"com.docker.compose.project", "maintainer"} {
+		if strings.Contains(receivedBody, label) {
+			t.Errorf("payload lists the container label %q.\nPayload: %s", label, receivedBody)
+		}
+	}
+}
+
 func TestGetColor(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -204,9 +359,15 @@ func TestGetIcon(t *testing.T) {
 			want:     ":skull_and_crossbones:",
 		},
 		{
-			name:     "oom kill",
+			name:     "sigkill exit",
 			action:   "die",
 			exitCode: "137",
+			want:     ":x:",
+		},
+		{
+			name:     "oom kill",
+			action:   "oom",
+			exitCode: "137",
 			want:     ":warning: :memory:",
 		},
 		{
@@ -0,0 +1,326 @@
+package notification
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Message parts rendered from templates
+const (
+	PartTitle   = "title"
+	PartBody    = "body"
+	PartSummary = "summary"
+)
+
+var messageParts = []string{PartTitle, PartBody, PartSummary}
+
+// Default templates, reproducing the fixed messages of earlier versions
+var defaultTemplates = map[string]string{
+	PartTitle: `Container Event: {{.ContainerName}}`,
+	PartBody: `Action: {{.Action}}
+{{- if eq .Action "health_status"}}
+{{- with label "health_status"}}
+Health Status: {{.}}{{end}}
+{{- with label "failing_streak"}}
+Failing Streak: {{.}}{{end}}
+{{- range .HealthLog}}
+Check ({{ago .End}}, exit {{.ExitCode}}): {{.Output}}{{end}}
+{{- else}}
+{{- with label "incident"}}
+Incident: {{.}}{{end}}
+{{- with label "image"}}
+Image: {{.}}{{end}}
+{{- with .ExitCode}}
+Exit Code: {{.}}{{end}}
+{{- if and .ExecDuration (ne .ExecDuration "N/A")}}
+Duration: {{.ExecDuration}}{{end}}
+{{- end}}
+Time: {{.Time}}`,
+	PartSummary: `{{.ContainerName}}: {{with label "incident"}}{{.}}{{else}}{{.Action}}{{with .ExitStatus}} (exit {{.}}){{end}}{{end}}`,
+
+	"crash_loop." + PartTitle: `Crash loop detected: {{.ContainerName}}`,
+	"crash_loop." + PartBody: `{{label "restarts"}} restarts in {{label "window"}}
+{{- with .ExitCode}}, last exit {{.}}{{end}}
+Time: {{.Time}}`,
+	"crash_loop_resolved." + PartTitle: `Crash loop resolved: {{.ContainerName}}`,
+	"crash_loop_resolved." + PartBody: `Up for {{label "stable_for"}} after {{label "restarts"}} restarts
+Time: {{.Time}}`,
+
+	"health_flapping." + PartTitle: `Health flapping: {{.ContainerName}}`,
+	"health_flapping." + PartBody: `Health keeps changing between healthy and unhealthy, currently {{label "health_status"}}.
+Health notifications are paused until it settles.
+Time: {{.Time}}`,
+	"health_flapping_stopped." + PartTitle: `Health stopped flapping: {{.ContainerName}}`,
+	"health_flapping_stopped." + PartBody: `Health Status: {{label "health_status"}}
+Changes not notified: {{label "suppressed"}}
+Time: {{.Time}}`,
+
+	"throttled." + PartTitle: `Notifications paused: {{.ContainerName}}`,
+	"throttled." + PartBody: `Over the limit of {{label "limit"}}, notifications are paused {{with label "paused_for"}}for {{.}}{{else}}until the next event{{end}}.
+Time: {{.Time}}`,
+	"throttled." + PartSummary:      `{{.ContainerName}}: notifications paused {{with label "paused_for"}}for {{.}}{{else}}until the next event{{end}}`,
+	"throttle_summary." + PartTitle: `Notifications resumed: {{.ContainerName}}`,
+	"throttle_summary." + PartBody: `{{label "suppressed"}} events suppressed in the last {{label "paused_for"}}: {{label "suppressed_actions"}}
+{{- with label "suppressed_exit_codes"}}
+Exit codes: {{.}}{{end}}
+{{- with label "suppressed_containers"}}
+Containers: {{.}}{{end}}
+Time: {{.Time}}`,
+	"throttle_summary." + PartSummary: `{{.ContainerName}}: {{label "suppressed"}} events suppressed in the last {{label "paused_for"}}: {{label "suppressed_actions"}}
+{{- with label "suppressed_exit_codes"}}, exit codes {{.}}{{end}}`,
+
+	"notifier_down." + PartTitle: `Notifier down: {{label "notifier"}}`,
+	"notifier_down." + PartBody: `Notifications to {{label "notifier"}} are failing and paused: {{label "error"}}
+Time: {{.Time}}`,
+	"notifier_down." + PartSummary:    `{{label "notifier"}} is down: {{label "error"}}`,
+	"notifier_recovered." + PartTitle: `Notifier recovered: {{label "notifier"}}`,
+	"notifier_recovered." + PartBody: `Notifications to {{label "notifier"}} are delivered again.
+Time: {{.Time}}`,
+	"notifier_recovered." + PartSummary: `{{label "notifier"}} recovered`,
+
+	"startup_inventory." + PartTitle: `Notidock started, monitoring {{label "monitored"}} containers`,
+	"startup_inventory." + PartBody: `Monitored: {{label "monitored"}}
+{{- with label "unhealthy"}}
+Unhealthy: {{.}}{{end}}
+{{- with label "exited"}}
+Exited: {{.}}{{end}}
+{{- if not (or (label "unhealthy") (label "exited"))}}
+All monitored containers are healthy{{end}}`,
+}
+
+// Templates render the title, body and summary of notification messages.
+// A template named after a part, such as "title", applies to every event;
+// one prefixed with an action, such as "die.title", replaces it for events
+// with that action.
+type Templates struct {
+	tmpl *template.Template
+}
+
+// builtinTemplates are used when no templates are configured
+var builtinTemplates = func() *Templates {
+	t, err := NewTemplates(nil)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}()
+
+// NewTemplates parses the given templates on top of the defaults
+func NewTemplates(templates map[string]string) (*Templates, error) {
+	root := template.New("").Funcs(templateFuncs(Event{}))
+	for name, text := range defaultTemplates {
+		if _, err := root.New(name).Parse(text); err != nil {
+			return nil, fmt.Errorf("template %s: %w", name, err)
+		}
+	}
+
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if err := validateTemplateName(name); err != nil {
+			return nil, err
+		}
+		if _, err := root.New(name).Parse(templates[name]); err != nil {
+			return nil, fmt.Errorf("template %s: %w", name, err)
+		}
+	}
+	return &Templates{tmpl: root}, nil
+}
+
+func validateTemplateName(name string) error {
+	part := name
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		if name[:i] == "" {
+			return fmt.Errorf("template %s: action must not be empty", name)
+		}
+		part = name[i+1:]
+	}
+	if !slices.Contains(messageParts, part) {
+		return fmt.Errorf("template %s: must be one of %s, optionally prefixed with an action", name, strings.Join(messageParts, ", "))
+	}
+	return nil
+}
+
+// Render renders one part of the message for the event
+func (t *Templates) Render(part string, event Event) (string, error) {
+	tmpl, err := t.tmpl.Clone()
+	if err != nil {
+		return "", err
+	}
+	tmpl.Funcs(templateFuncs(event))
+
+	name := event.Action + "." + part
+	if tmpl.Lookup(name) == nil {
+		name = part
+	}
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, name, event); err != nil {
+		return "", fmt.Errorf("template %s: %w", name, err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// Apply returns the event with its title, body and summary rendered. A part
+// that fails to render falls back to the default template.
+func (t *Templates) Apply(event Event) (Event, error) {
+	var errs []error
+	for _, part := range messageParts {
+		text, err := t.Render(part, event)
+		if err != nil {
+			errs = append(errs, err)
+			text, _ = builtinTemplates.Render(part, event)
+		}
+		switch part {
+		case PartTitle:
+			event.Title = text
+		case PartBody:
+			event.Body = text
+		case PartSummary:
+			event.Summary = text
+		}
+	}
+	if len(errs) > 0 {
+		return event, errs[0]
+	}
+	return event, nil
+}
+
+// withMessage renders the default message for events that were not
+// rendered by a Manager, such as events sent to a notifier directly
+func withMessage(event Event) Event {
+	if event.Title != "" || event.Body != "" {
+		return event
+	}
+	event, _ = builtinTemplates.Apply(event)
+	return event
+}
+
+func templateFuncs(event Event) template.FuncMap {
+	return template.FuncMap{
+		"exitExplain":   ExitCodeExplanation,
+		"humanDuration": HumanDuration,
+		"ago": func(t time.Time) string {
+			return timeAgo(t, time.Now())
+		},
+		"label": func(key string) string {
+			return event.Labels[key]
+		},
+		"truncate": truncate,
+	}
+}
+
+// truncate shortens s to at most n characters, marking the cut with an
+// ellipsis
+func truncate(n int, s string) string {
+	runes := []rune(s)
+	if n < 0 || len(runes) <= n {
+		return s
+	}
+	if n == 0 {
+		return ""
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+// HumanDuration formats d as days, hours and minutes, or minutes and
+// seconds for shorter durations, e.g. "3h 0m" or "1m 30s"
+func HumanDuration(d time.Duration) string {
+	days := d / (24 * time.Hour)
+	d = d % (24 * time.Hour)
+
+	hours := d / time.Hour
+	d = d % time.Hour
+
+	minutes := d / time.Minute
+	d = d % time.Minute
+
+	if days > 0 {
+		return fmt.Sprintf("%dd %dh %dm", days, hours, minutes)
+	}
+	if hours > 0 {
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	}
+	if minutes > 0 {
+		return fmt.Sprintf("%dm %ds", minutes, d/time.Second)
+	}
+	return fmt.Sprintf("%ds", d/time.Second)
+}
+
+// ExitCodeExplanation describes a container exit code, or returns "" when
+// code is not a number
+func ExitCodeExplanation(code string) string {
+	if code == "" {
+		return ""
+	}
+
+	// Convert string to int for switch statement
+	// If conversion fails, return empty string
+	codeInt, err := strconv.Atoi(code)
+	if err != nil {
+		return ""
+	}
+
+	switch codeInt {
+	// Standard Linux exit codes
+	case 0:
+		return "(Success) Container exited normally"
+	case 1:
+		return "(Error) Container exited with general error"
+	case 2:
+		return "(Error) Container exited due to misuse of shell builtins"
+	case 126:
+		return "(Error) Command invoked cannot execute"
+	case 127:
+		return "(Error) Command not found"
+	case 128:
+		return "(Error) Invalid exit argument"
+	case 130:
+		return "(Terminated) Container terminated by Ctrl-C"
+	case 137:
+		return "(SIGKILL) Container received kill signal or exceeded memory limit"
+	case 139:
+		return "(SIGSEGV) Container crashed with segmentation fault"
+	case 143:
+		return "(SIGTERM) Container received termination signal"
+
+	// Docker specific codes
+	case 255:
+		return "(Error) Container exited with Docker fatal error"
+
+	// Special Docker signal offset cases (128 + signal number)
+	case 129: // 128 + 1 (SIGHUP)
+		return "(SIGHUP) Container terminated by hangup"
+	case 131: // 128 + 3 (SIGQUIT)
+		return "(SIGQUIT) Container quit by quit signal"
+	case 132: // 128 + 4 (SIGILL)
+		return "(SIGILL) Container terminated by illegal instruction"
+	case 134: // 128 + 6 (SIGABRT)
+		return "(SIGABRT) Container aborted"
+	case 135: // 128 + 7 (SIGBUS)
+		return "(SIGBUS) Container terminated by bus error"
+	case 136: // 128 + 8 (SIGFPE)
+		return "(SIGFPE) Container terminated by floating point exception"
+	case 138: // 128 + 10 (SIGUSR1)
+		return "(SIGUSR1) Container terminated by user-defined signal 1"
+	case 140: // 128 + 12 (SIGUSR2)
+		return "(SIGUSR2) Container terminated by user-defined signal 2"
+	case 141: // 128 + 13 (SIGPIPE)
+		return "(SIGPIPE) Container terminated by broken pipe"
+	case 142: // 128 + 14 (SIGALRM)
+		return "(SIGALRM) Container terminated by timer"
+
+	default:
+		if codeInt > 128 {
+			return fmt.Sprintf("(Signal %d) Container terminated by signal %d", codeInt-128, codeInt-128)
+		}
+		return fmt.Sprintf("(Code %d) Unknown exit code", codeInt)
+	}
+}
@@ -0,0 +1,189 @@
+package notification
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTemplates_Defaults(t *testing.T) {
+	tests := []struct {
+		name  string
+		event Event
+		title string
+		body  string
+	}{
+		{
+			name: "die",
+			event: Event{
+				ContainerName: "payments-api",
+				Action:        "die",
+				Time:          "2024-12-14T17:34:36Z",
+				Labels:        map[string]string{"image": "payments:1.2"},
+				ExitCode:      "1 (Error) Container exited with general error",
+				ExecDuration:  "3h 0m",
+			},
+			title: "Container Event: payments-api",
+			body: "Action: die\n" +
+				"Image: payments:1.2\n" +
+				"Exit Code: 1 (Error) Container exited with general error\n" +
+				"Duration: 3h 0m\n" +
+				"Time: 2024-12-14T17:34:36Z",
+		},
+		{
+			name: "correlated stop",
+			event: Event{
+				ContainerName: "payments-api",
+				Action:        "die",
+				Time:          "2024-12-14T17:34:36Z",
+				Labels:        map[string]string{"actions": "kill,die,stop", "incident": "stopped by SIGTERM, exit 143 after 2h 3m"},
+				ExitCode:      "143 (SIGTERM) Container received shutdown signal",
+			},
+			title: "Container Event: payments-api",
+			body: "Action: die\n" +
+				"Incident: stopped by SIGTERM, exit 143 after 2h 3m\n" +
+				"Exit Code: 143 (SIGTERM) Container received shutdown signal\n" +
+				"Time: 2024-12-14T17:34:36Z",
+		},
+		{
+			name: "throttle summary",
+			event: Event{
+				ContainerName: "payments-api",
+				Action:        "throttle_summary",
+				Time:          "2024-12-14T17:34:36Z",
+				Labels: map[string]string{
+					"suppressed":            "37",
+					"paused_for":            "5m0s",
+					"suppressed_actions":    "18 die, 18 start, 1 oom",
+					"suppressed_exit_codes": "1×17, 137×1",
+				},
+			},
+			title: "Notifications resumed: payments-api",
+			body: "37 events suppressed in the last 5m0s: 18 die, 18 start, 1 oom\n" +
+				"Exit codes: 1×17, 137×1\n" +
+				"Time: 2024-12-14T17:34:36Z",
+		},
+		{
+			name: "health status",
+			event: Event{
+				ContainerName: "web",
+				Action:        "health_status",
+				Time:          "2024-12-14T17:34:36Z",
+				Labels:        map[string]string{"health_status": "unhealthy", "failing_streak": "3"},
+			},
+			title: "Container Event: web",
+			body: "Action: health_status\n" +
+				"Health Status: unhealthy\n" +
+				"Failing Streak: 3\n" +
+				"Time: 2024-12-14T17:34:36Z",
+		},
+		{
+			name:  "without duration",
+			event: Event{ContainerName: "web", Action: "start", Time: "2024-12-14T17:34:36Z", ExecDuration: "N/A"},
+			title: "Container Event: web",
+			body:  "Action: start\nTime: 2024-12-14T17:34:36Z",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := builtinTemplates.Apply(tt.event)
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if got.Title != tt.title {
+				t.Errorf("title = %q, want %q", got.Title, tt.title)
+			}
+			if got.Body != tt.body {
+				t.Errorf("body = %q, want %q", got.Body, tt.body)
+			}
+		})
+	}
+}
+
+func TestTemplates_Custom(t *testing.T) {
+	templates, err := NewTemplates(map[string]string{
+		"die.title": `{{.ContainerName}} crashed (exit {{.ExitStatus}}{{if eq .ExitStatus "137"}}, OOM{{end}}) after {{humanDuration .Runtime}}`,
+		"summary":   `{{truncate 12 .ContainerName}} {{.Action}} in {{label "com.docker.compose.project"}}`,
+		"body":      `{{exitExplain .ExitStatus}}`,
+	})
+	if err != nil {
+		t.Fatalf("NewTemplates() error = %v", err)
+	}
+
+	event := Event{
+		ContainerName: "payments-api-worker",
+		Action:        "die",
+		ExitStatus:    "137",
+		Runtime:       3 * time.Hour,
+		Labels:        map[string]string{"com.docker.compose.project": "prod"},
+	}
+	got, err := templates.Apply(event)
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if want := "payments-api-worker crashed (exit 137, OOM) after 3h 0m"; got.Title != want {
+		t.Errorf("title = %q, want %q", got.Title, want)
+	}
+	if want := "payments-ap… die in prod"; got.Summary != want {
+		t.Errorf("summary = %q, want %q", got.Summary, want)
+	}
+	if want := "(SIGKILL) Container received kill signal or exceeded memory limit"; got.Body != want {
+		t.Errorf("body = %q, want %q", got.Body, want)
+	}
+
+	// Other actions keep the default title
+	started, _ := templates.Apply(Event{ContainerName: "web", Action: "start"})
+	if want := "Container Event: web"; started.Title != want {
+		t.Errorf("title = %q, want %q", started.Title, want)
+	}
+}
+
+func TestNewTemplates_Errors(t *testing.T) {
+	tests := map[string]map[string]string{
+		"unknown part": {"die.subject": "{{.ContainerName}}"},
+		"empty action": {".title": "{{.ContainerName}}"},
+		"syntax error": {"title": "{{.ContainerName"},
+		"unknown func": {"title": "{{shout .ContainerName}}"},
+	}
+	for name, templates := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := NewTemplates(templates); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestTemplates_RenderErrorFallsBack(t *testing.T) {
+	templates, err := NewTemplates(map[string]string{"title": "{{.Missing}}"})
+	if err != nil {
+		t.Fatalf("NewTemplates() error = %v", err)
+	}
+
+	got, err := templates.Apply(Event{ContainerName: "web", Action: "start"})
+	if err == nil {
+		t.Error("expected a render error")
+	}
+	if want := "Container Event: web"; got.Title != want {
+		t.Errorf("title = %q, want the default %q", got.Title, want)
+	}
+}
+
+func TestManager_SetTemplates(t *testing.T) {
+	notifier := NewMockNotifier("mock")
+	manager := NewManager(ManagerOptions{}, notifier)
+
+	templates, err := NewTemplates(map[string]string{"title": "{{.ContainerName}} {{.Action}}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager.SetTemplates(templates)
+	manager.Send(context.Background(), Event{ContainerName: "web", Action: "stop"})
+	manager.Close(context.Background())
+
+	events := notifier.GetEvents()
+	if len(events) != 1 || events[0].Title != "web stop" {
+		t.Errorf("notifier received %+v, want title %q", events, "web stop")
+	}
+}
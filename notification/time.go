@@ -0,0 +1,65 @@
+package notification
+
+import (
+	"fmt"
+	"time"
+)
+
+// Named layouts accepted in place of a Go time layout
+var timeLayouts = map[string]string{
+	"RFC3339":  time.RFC3339,
+	"RFC1123":  time.RFC1123,
+	"RFC822":   time.RFC822,
+	"DateTime": time.DateTime,
+	"Kitchen":  time.Kitchen,
+	"Stamp":    time.Stamp,
+}
+
+// TimeFormat renders event timestamps as the Time of an event
+type TimeFormat struct {
+	// Location is the time zone times are shown in, time.Local if nil
+	Location *time.Location
+	// Layout is a Go time layout or one of RFC3339, RFC1123, RFC822,
+	// DateTime, Kitchen or Stamp. Defaults to RFC3339.
+	Layout string
+	// Relative appends how long ago the event happened, e.g. "(2m ago)"
+	Relative bool
+}
+
+var defaultTimeFormat = TimeFormat{Location: time.Local, Layout: time.RFC3339}
+
+// Format renders t, with the age relative to now if enabled
+func (f TimeFormat) Format(t, now time.Time) string {
+	loc := f.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	layout := f.Layout
+	if named, ok := timeLayouts[layout]; ok {
+		layout = named
+	}
+	if layout == "" {
+		layout = time.RFC3339
+	}
+
+	s := t.In(loc).Format(layout)
+	if f.Relative {
+		s += " (" + timeAgo(t, now) + ")"
+	}
+	return s
+}
+
+// timeAgo describes how long before now t was, in its largest unit
+func timeAgo(t, now time.Time) string {
+	d := now.Sub(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", d/time.Minute)
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", d/time.Hour)
+	default:
+		return fmt.Sprintf("%dd ago", d/(24*time.Hour))
+	}
+}
@@ -0,0 +1,57 @@
+package notification
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTimeFormat_Format(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("time zone database not available")
+	}
+	ts := time.Date(2024, 12, 14, 17, 34, 36, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		format TimeFormat
+		now    time.Time
+		want   string
+	}{
+		{"default layout", TimeFormat{Location: time.UTC}, ts, "2024-12-14T17:34:36Z"},
+		{"time zone", TimeFormat{Location: berlin, Layout: "RFC3339"}, ts, "2024-12-14T18:34:36+01:00"},
+		{"named layout", TimeFormat{Location: berlin, Layout: "DateTime"}, ts, "2024-12-14 18:34:36"},
+		{"go layout", TimeFormat{Location: time.UTC, Layout: "Jan 2 15:04"}, ts, "Dec 14 17:34"},
+		{"just now", TimeFormat{Location: time.UTC, Layout: "15:04", Relative: true}, ts.Add(30 * time.Second), "17:34 (just now)"},
+		{"minutes ago", TimeFormat{Location: time.UTC, Layout: "15:04", Relative: true}, ts.Add(2 * time.Minute), "17:34 (2m ago)"},
+		{"hours ago", TimeFormat{Location: time.UTC, Layout: "15:04", Relative: true}, ts.Add(3*time.Hour + 59*time.Minute), "17:34 (3h ago)"},
+		{"days ago", TimeFormat{Location: time.UTC, Layout: "15:04", Relative: true}, ts.Add(50 * time.Hour), "17:34 (2d ago)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.format.Format(ts, tt.now); got != tt.want {
+				t.Errorf("Format() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManager_SetTimeFormat(t *testing.T) {
+	notifier := NewMockNotifier("mock")
+	manager := NewManager(ManagerOptions{}, notifier)
+	manager.SetTimeFormat(TimeFormat{Location: time.UTC, Layout: "2006-01-02 15:04"})
+
+	ts := time.Date(2024, 12, 14, 17, 34, 36, 0, time.UTC)
+	manager.Send(context.Background(), Event{ContainerName: "web", Action: "start", Timestamp: ts})
+	manager.Close(context.Background())
+
+	events := notifier.GetEvents()
+	if len(events) != 1 || events[0].Time != "2024-12-14 17:34" {
+		t.Fatalf("notifier received %+v", events)
+	}
+	if want := "Action: start\nTime: 2024-12-14 17:34"; events[0].Body != want {
+		t.Errorf("body = %q, want %q", events[0].Body, want)
+	}
+}
@@ -0,0 +1,168 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrHostNotAllowed is reported when an event asks to be delivered to a
+// webhook whose host is not on the allowlist
+var ErrHostNotAllowed = errors.New("webhook host not allowed")
+
+// WebhookNotifier posts events as JSON to a webhook. Containers can pick
+// their own webhook with a label, restricted to the allowed hosts so that a
+// container cannot send events to arbitrary URLs.
+type WebhookNotifier struct {
+	name         string
+	url          string
+	allowedHosts []string
+	client       *http.Client
+}
+
+// WebhookOptions configures a webhook notifier
+type WebhookOptions struct {
+	// Name identifies the notifier in routes and logs, "webhook" by default
+	Name string
+	// URL is the default webhook; it may be empty when only containers
+	// choose where their events go
+	URL          string
+	AllowedHosts []string
+}
+
+func NewWebhookNotifier() (*WebhookNotifier, error) {
+	var env envVars
+	opts := WebhookOptions{
+		URL:          env.get("NOTIDOCK_WEBHOOK_URL"),
+		AllowedHosts: splitList(env.get("NOTIDOCK_WEBHOOK_ALLOWED_HOSTS")),
+	}
+	if env.err != nil {
+		return nil, env.err
+	}
+	if opts.URL == "" && len(opts.AllowedHosts) == 0 {
+		return nil, ErrNotConfigured
+	}
+	return NewWebhookNotifierWithOptions(opts)
+}
+
+// NewWebhookNotifierWithOptions creates a webhook notifier from explicit
+// settings
+func NewWebhookNotifierWithOptions(opts WebhookOptions) (*WebhookNotifier, error) {
+	if opts.URL != "" {
+		if _, err := parseWebhookURL(opts.URL); err != nil {
+			return nil, fmt.Errorf("invalid webhook URL: %w", redactURLError(err))
+		}
+	}
+	allowedHosts := make([]string, 0, len(opts.AllowedHosts))
+	for _, host := range opts.AllowedHosts {
+		allowedHosts = append(allowedHosts, strings.ToLower(host))
+	}
+	if opts.Name == "" {
+		opts.Name = "webhook"
+	}
+
+	return &WebhookNotifier{
+		name:         opts.Name,
+		url:          opts.URL,
+		allowedHosts: allowedHosts,
+		client:       &http.Client{},
+	}, nil
+}
+
+func (w *WebhookNotifier) Name() string {
+	return w.name
+}
+
+// Send implements the Notifier interface for webhooks. Events without an
+// allowed webhook of their own go to NOTIDOCK_WEBHOOK_URL, or nowhere if it
+// is unset.
+func (w *WebhookNotifier) Send(ctx context.Context, event Event) error {
+	target := w.url
+	if event.Target != nil && event.Target.WebhookURL != "" {
+		// A misconfigured container must not trip the circuit breaker for
+		// everyone else, so its events fall back to the default webhook
+		if err := w.checkHost(event.Target.WebhookURL); err != nil {
+			slog.Warn("ignoring webhook requested by container",
+				"containerName", event.ContainerName,
+				"error", err,
+			)
+		} else {
+			target = event.Target.WebhookURL
+		}
+	}
+	if target == "" {
+		return nil
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook notification: %w", redactURLError(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return newHTTPError(resp, fmt.Errorf("webhook notification failed with status code: %d", resp.StatusCode))
+	}
+	return nil
+}
+
+// checkHost verifies that rawURL points at an allowed host. Entries of the
+// form "*.example.com" allow every subdomain of example.com.
+func (w *WebhookNotifier) checkHost(rawURL string) error {
+	u, err := parseWebhookURL(rawURL)
+	if err != nil {
+		return err
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range w.allowedHosts {
+		if host == allowed {
+			return nil
+		}
+		if suffix, ok := strings.CutPrefix(allowed, "*"); ok && strings.HasPrefix(suffix, ".") && strings.HasSuffix(host, suffix) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrHostNotAllowed, host)
+}
+
+func parseWebhookURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return nil, errors.New("must use http or https")
+	}
+	if u.Host == "" {
+		return nil, errors.New("missing host")
+	}
+	return u, nil
+}
+
+// splitList splits a comma-separated list, dropping empty entries
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if trimmed := strings.TrimSpace(item); trimmed != "" {
+			items = append(items, trimmed)
+		}
+	}
+	return items
+}
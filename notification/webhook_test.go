@@ -0,0 +1,117 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestNewWebhookNotifier(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr bool
+	}{
+		{
+			name: "default webhook",
+			env:  map[string]string{"NOTIDOCK_WEBHOOK_URL": "https://hooks.example.com/notidock"},
+		},
+		{
+			name: "allowlist only",
+			env:  map[string]string{"NOTIDOCK_WEBHOOK_ALLOWED_HOSTS": "hooks.example.com, *.internal.example.com"},
+		},
+		{
+			name:    "not configured",
+			env:     map[string]string{},
+			wantErr: true,
+		},
+		{
+			name:    "invalid scheme",
+			env:     map[string]string{"NOTIDOCK_WEBHOOK_URL": "ftp://hooks.example.com"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NOTIDOCK_WEBHOOK_URL", "")
+			t.Setenv("NOTIDOCK_WEBHOOK_ALLOWED_HOSTS", "")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			_, err := NewWebhookNotifier()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewWebhookNotifier() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWebhookNotifier_CheckHost(t *testing.T) {
+	notifier := &WebhookNotifier{allowedHosts: []string{"hooks.example.com", "*.internal.example.com"}}
+
+	tests := []struct {
+		url     string
+		allowed bool
+	}{
+		{"https://hooks.example.com/team-payments", true},
+		{"https://HOOKS.example.com:8443/x", true},
+		{"https://alerts.internal.example.com/x", true},
+		{"https://internal.example.com/x", false},
+		{"https://hooks.example.com.attacker.net/x", false},
+		{"https://attacker.net/?u=hooks.example.com", false},
+		{"file:///etc/passwd", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			err := notifier.checkHost(tt.url)
+			if (err == nil) != tt.allowed {
+				t.Errorf("checkHost() error = %v, want allowed %v", err, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestWebhookNotifier_Send(t *testing.T) {
+	var mu sync.Mutex
+	received := map[string][]Event{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("invalid payload: %v", err)
+		}
+		mu.Lock()
+		received[r.URL.Path] = append(received[r.URL.Path], event)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	notifier := &WebhookNotifier{
+		url:          server.URL + "/default",
+		allowedHosts: []string{"127.0.0.1"},
+		client:       server.Client(),
+	}
+
+	events := []Event{
+		{ContainerName: "web", Action: "die"},
+		{ContainerName: "payments", Action: "die", Target: &Target{WebhookURL: server.URL + "/payments"}},
+		{ContainerName: "rogue", Action: "die", Target: &Target{WebhookURL: "https://attacker.net/collect"}},
+	}
+	for _, e := range events {
+		if err := notifier.Send(context.Background(), e); err != nil {
+			t.Fatalf("Send(%s) error = %v", e.ContainerName, err)
+		}
+	}
+
+	if got := received["/payments"]; len(got) != 1 || got[0].ContainerName != "payments" {
+		t.Errorf("team webhook received %+v", got)
+	}
+	if got := received["/default"]; len(got) != 2 || got[1].ContainerName != "rogue" {
+		t.Errorf("disallowed webhook should fall back to the default, default received %+v", got)
+	}
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"notidock/config"
+	"notidock/notification"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// LabelReconciled marks events that were not received from Docker but
+// reconstructed on startup for changes made while notidock was not running
+const LabelReconciled = "reconciled"
+
+// stateFlushInterval is how often changes to the container state are
+// written, so a burst of events does not write the file for every one
+const stateFlushInterval = 10 * time.Second
+
+// containerState is the last known state of a container
+type containerState struct {
+	// Attributes are the attributes of the container's events: its labels,
+	// name and image
+	Attributes map[string]string `json:"attributes"`
+	Running    bool              `json:"running"`
+	ExitCode   string            `json:"exit_code,omitempty"`
+}
+
+// stateStore keeps the last known state of every container in a JSON file,
+// so that changes while notidock was not running can be detected. It is
+// used from the main loop only.
+type stateStore struct {
+	path string
+	// dirty is set when the containers changed since the last save
+	dirty bool
+	// SeenAt is when the state was last saved. Containers that started or
+	// exited after it did so while notidock was not watching.
+	SeenAt     time.Time                 `json:"seen_at"`
+	Containers map[string]containerState `json:"containers"`
+}
+
+// loadStateStore reads the container state from path. A missing file
+// results in an empty store with a zero SeenAt.
+func loadStateStore(path string) (*stateStore, error) {
+	s := &stateStore{path: path, Containers: make(map[string]containerState)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read container state: %w", err)
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to read container state %s: %w", path, err)
+	}
+	if s.Containers == nil {
+		s.Containers = make(map[string]containerState)
+	}
+	return s, nil
+}
+
+// Save writes the state, marking it as seen now
+func (s *stateStore) Save() error {
+	s.SeenAt = time.Now().UTC()
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal container state: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("failed to write container state: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write container state: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write container state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write container state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to write container state: %w", err)
+	}
+	s.dirty = false
+	return nil
+}
+
+// Flush saves the state if it changed since it was last saved
+func (s *stateStore) Flush() {
+	if !s.dirty {
+		return
+	}
+	if err := s.Save(); err != nil {
+		slog.Warn("failed to save container state", "error", err)
+	}
+}
+
+// Observe records the state change of a lifecycle event. The change is
+// saved by the next Flush.
+func (s *stateStore) Observe(event Event) {
+	id := event.Actor.ID
+	switch event.Action {
+	case "start":
+		s.Containers[id] = containerState{Attributes: stateAttributes(event.Actor.Attributes), Running: true}
+	case "die":
+		s.Containers[id] = containerState{
+			Attributes: stateAttributes(event.Actor.Attributes),
+			ExitCode:   event.Actor.Attributes["exitCode"],
+		}
+	case "destroy":
+		if _, ok := s.Containers[id]; !ok {
+			return
+		}
+		delete(s.Containers, id)
+	default:
+		return
+	}
+	s.dirty = true
+}
+
+// stateAttributes drops the attributes describing a single event
+func stateAttributes(attributes map[string]string) map[string]string {
+	a := maps.Clone(attributes)
+	delete(a, "exitCode")
+	delete(a, "execDuration")
+	delete(a, "signal")
+	return a
+}
+
+// dockerAPI is the part of the Docker client used for reconciliation
+type dockerAPI interface {
+	containerLister
+	containerInspector
+}
+
+// reconcile compares the containers with the state saved when notidock last
+// ran. It returns the events for the changes missed in between, oldest
+// first, and replaces the saved state with the current one. Monitored
+// containers that are exited despite restart policy "always" are reported
+// once, even without saved state.
+func reconcile(ctx context.Context, api dockerAPI, cfg config.AppConfig, store *stateStore) ([]Event, error) {
+	containers, err := api.ContainerList(ctx, container.ListOptions{All: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	var events []Event
+	current := make(map[string]containerState, len(containers))
+	for _, c := range containers {
+		attributes := containerAttributes(c)
+		if !shouldMonitorContainer(cfg, attributes) {
+			continue
+		}
+		info, err := api.ContainerInspect(ctx, c.ID)
+		if err != nil {
+			slog.Warn("failed to inspect container", "containerID", c.ID, "error", err)
+			continue
+		}
+		if info.ContainerJSONBase == nil || info.State == nil {
+			continue
+		}
+
+		state := containerState{Attributes: attributes, Running: info.State.Running}
+		if !state.Running {
+			state.ExitCode = strconv.Itoa(info.State.ExitCode)
+		}
+		current[c.ID] = state
+		events = append(events, missedEvents(c.ID, info, state, store)...)
+	}
+
+	if !store.SeenAt.IsZero() {
+		for id, previous := range store.Containers {
+			if _, exists := current[id]; exists || !previous.Running {
+				continue
+			}
+			if !shouldMonitorContainer(cfg, previous.Attributes) {
+				continue
+			}
+			// Removed while running, so it died after notidock stopped. Its
+			// exit code is gone with it.
+			attributes := maps.Clone(previous.Attributes)
+			attributes[LabelReconciled] = "true"
+			attributes["unexpected_state"] = "removed while running"
+			events = append(events, Event{
+				Type:   "container",
+				Action: "die",
+				Actor:  Actor{ID: id, Attributes: attributes},
+				Time:   store.SeenAt.Unix(),
+			})
+		}
+	}
+
+	slices.SortStableFunc(events, func(a, b Event) int {
+		return eventTime(a).Compare(eventTime(b))
+	})
+	store.Containers = current
+	return events, nil
+}
+
+// missedEvents returns the events for a change of the container while
+// notidock was not running, if any. Only lifecycle events tracked by
+// default are returned, so that they are not filtered out.
+func missedEvents(id string, info types.ContainerJSON, state containerState, store *stateStore) []Event {
+	previous, known := store.Containers[id]
+	hasState := !store.SeenAt.IsZero()
+	startedAt, _ := time.Parse(time.RFC3339Nano, info.State.StartedAt)
+	finishedAt, _ := time.Parse(time.RFC3339Nano, info.State.FinishedAt)
+
+	attributes := maps.Clone(state.Attributes)
+	attributes[LabelReconciled] = "true"
+	event := Event{Type: "container", Actor: Actor{ID: id, Attributes: attributes}}
+
+	if state.Running {
+		if !hasState || !startedAt.After(store.SeenAt) {
+			return nil
+		}
+		event.Action = "start"
+		event.Time, event.TimeNano = startedAt.Unix(), startedAt.UnixNano()
+		if !known || !previous.Running {
+			return []Event{event}
+		}
+		// Restarted, so it died first. Docker resets the exit code on start,
+		// so it is unknown.
+		died := Event{Type: "container", Action: "die", Actor: Actor{ID: id, Attributes: maps.Clone(attributes)}}
+		died.Time, died.TimeNano = event.Time, event.TimeNano
+		if finishedAt.After(store.SeenAt) && !finishedAt.After(startedAt) {
+			died.Time, died.TimeNano = finishedAt.Unix(), finishedAt.UnixNano()
+		}
+		return []Event{died, event}
+	}
+
+	// A container that was created but never started has not exited
+	if info.State.Status == "created" || startedAt.IsZero() || finishedAt.IsZero() {
+		return nil
+	}
+	attributes["exitCode"] = state.ExitCode
+	if !startedAt.IsZero() && finishedAt.After(startedAt) {
+		attributes["execDuration"] = strconv.FormatInt(int64(finishedAt.Sub(startedAt)/time.Second), 10)
+	}
+	event.Action = "die"
+	event.Time, event.TimeNano = finishedAt.Unix(), finishedAt.UnixNano()
+
+	switch {
+	case hasState && finishedAt.After(store.SeenAt):
+		return []Event{event}
+	case known && previous.Running:
+		// Its die event was never handled
+		return []Event{event}
+	case known:
+		// Already exited when notidock last ran
+		return nil
+	case info.HostConfig != nil && info.HostConfig.RestartPolicy.Name == container.RestartPolicyAlways:
+		attributes["unexpected_state"] = "exited despite restart policy always"
+		return []Event{event}
+	}
+	return nil
+}
+
+// reconcileOnStartup notifies the changes missed while notidock was not
+// running and returns the store that keeps the container state from now on,
+// or nil when it cannot be used
+func reconcileOnStartup(ctx context.Context, api dockerAPI, cfg config.AppConfig, notificationManager *notification.Manager, throttler *NotificationThrottler, health *healthTracker, crashLoops *crashLoopDetector, correlator *eventCorrelator) *stateStore {
+	store, err := loadStateStore(filepath.Join(cfg.StateDir, "containers.json"))
+	if err != nil {
+		slog.Error("container state disabled", "error", err)
+		return nil
+	}
+
+	events, err := reconcile(ctx, api, cfg, store)
+	if err != nil {
+		slog.Error("failed to reconcile container state", "error", err)
+		return store
+	}
+	slog.Info("container state reconciled", "missed_events", len(events), "last_seen", store.SeenAt)
+	for _, event := range events {
+		handleContainerEvent(ctx, event, cfg, notificationManager, throttler, health, crashLoops, correlator)
+	}
+	if err := store.Save(); err != nil {
+		slog.Warn("failed to save container state", "error", err)
+	}
+	return store
+}
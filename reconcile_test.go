@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"io/fs"
+	"notidock/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeDocker lists containers and inspects them from their inspect results
+type fakeDocker map[string]types.ContainerJSON
+
+func (f fakeDocker) ContainerList(context.Context, container.ListOptions) ([]types.Container, error) {
+	var list []types.Container
+	for id, info := range f {
+		list = append(list, types.Container{ID: id, Names: []string{"/" + info.Name}})
+	}
+	return list, nil
+}
+
+func (f fakeDocker) ContainerInspect(_ context.Context, id string) (types.ContainerJSON, error) {
+	info, ok := f[id]
+	if !ok {
+		return types.ContainerJSON{}, errors.New("no such container")
+	}
+	return info, nil
+}
+
+func inspected(name string, running bool, exitCode int, startedAt, finishedAt time.Time, policy container.RestartPolicyMode) types.ContainerJSON {
+	return types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{
+		Name: name,
+		State: &types.ContainerState{
+			Running:    running,
+			ExitCode:   exitCode,
+			StartedAt:  startedAt.Format(time.RFC3339Nano),
+			FinishedAt: finishedAt.Format(time.RFC3339Nano),
+		},
+		HostConfig: &container.HostConfig{RestartPolicy: container.RestartPolicy{Name: policy}},
+	}}
+}
+
+func TestReconcile(t *testing.T) {
+	cfg := getTestConfig()
+	cfg.TrackedEvents = strings.Split(config.DefaultTrackedEvents, ",")
+	seenAt := time.Now().Add(-time.Hour).UTC()
+	before := seenAt.Add(-time.Hour)
+	after := seenAt.Add(10 * time.Minute)
+
+	store := &stateStore{
+		path:   filepath.Join(t.TempDir(), "containers.json"),
+		SeenAt: seenAt,
+		Containers: map[string]containerState{
+			"crashed":   {Attributes: map[string]string{"name": "crashed"}, Running: true},
+			"restarted": {Attributes: map[string]string{"name": "restarted"}, Running: true},
+			"stopped":   {Attributes: map[string]string{"name": "stopped"}, ExitCode: "0"},
+			"unchanged": {Attributes: map[string]string{"name": "unchanged"}, Running: true},
+			"removed":   {Attributes: map[string]string{"name": "removed"}, Running: true},
+		},
+	}
+	docker := fakeDocker{
+		"crashed":   inspected("crashed", false, 137, before, after, container.RestartPolicyDisabled),
+		"restarted": inspected("restarted", true, 0, after.Add(time.Minute), after.Add(30*time.Second), container.RestartPolicyAlways),
+		"stopped":   inspected("stopped", false, 0, before, before, container.RestartPolicyAlways),
+		"unchanged": inspected("unchanged", true, 0, before, time.Time{}, container.RestartPolicyDisabled),
+		"new":       inspected("new", true, 0, after.Add(2*time.Minute), time.Time{}, container.RestartPolicyDisabled),
+	}
+
+	events, err := reconcile(context.Background(), docker, cfg, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, e := range events {
+		if e.Actor.Attributes[LabelReconciled] != "true" {
+			t.Errorf("%s event of %s is not marked as reconciled", e.Action, e.Actor.ID)
+		}
+		if !shouldTrackEvent(cfg, e.Action, e.Actor.Attributes) {
+			t.Errorf("%s event of %s is not tracked by default", e.Action, e.Actor.ID)
+		}
+		got = append(got, e.Action+" "+e.Actor.ID)
+	}
+	want := []string{"die removed", "die crashed", "die restarted", "start restarted", "start new"}
+	if len(got) != len(want) {
+		t.Fatalf("events = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+	if code := events[1].Actor.Attributes["exitCode"]; code != "137" {
+		t.Errorf("exit code = %q, want 137", code)
+	}
+	if events[0].Actor.Attributes["unexpected_state"] == "" {
+		t.Error("die of removed does not explain the unexpected state")
+	}
+	if _, ok := store.Containers["removed"]; ok {
+		t.Error("removed container is still in the state")
+	}
+	if len(store.Containers) != 5 {
+		t.Errorf("state has %d containers, want 5", len(store.Containers))
+	}
+}
+
+func TestReconcile_WithoutState(t *testing.T) {
+	cfg := getTestConfig()
+	store := &stateStore{path: filepath.Join(t.TempDir(), "containers.json"), Containers: map[string]containerState{}}
+	long := time.Now().Add(-24 * time.Hour)
+	docker := fakeDocker{
+		"web":    inspected("web", false, 1, long, long.Add(time.Hour), container.RestartPolicyAlways),
+		"job":    inspected("job", false, 1, long, long.Add(time.Hour), container.RestartPolicyDisabled),
+		"worker": inspected("worker", true, 0, long, time.Time{}, container.RestartPolicyAlways),
+		// Created but never started, with Docker's zero start and finish times
+		"created": inspected("created", false, 0, time.Time{}, time.Time{}, container.RestartPolicyAlways),
+	}
+	docker["created"].State.Status = "created"
+
+	events, err := reconcile(context.Background(), docker, cfg, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Actor.ID != "web" || events[0].Action != "die" {
+		t.Fatalf("events = %+v, want only the die of web", events)
+	}
+	if events[0].Actor.Attributes["unexpected_state"] == "" {
+		t.Error("die of web does not explain the unexpected state")
+	}
+
+	// Reported once: the exited state is known on the next start
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadStateStore(store.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if events, _ := reconcile(context.Background(), docker, cfg, loaded); len(events) != 0 {
+		t.Errorf("second reconciliation returned %+v", events)
+	}
+}
+
+func TestStateStore_Observe(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "containers.json")
+	store, err := loadStateStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !store.SeenAt.IsZero() || len(store.Containers) != 0 {
+		t.Fatalf("new store = %+v, want it empty", store)
+	}
+
+	attributes := map[string]string{"name": "web", "exitCode": "1", "execDuration": "30"}
+	store.Observe(Event{Action: "start", Actor: Actor{ID: "a", Attributes: map[string]string{"name": "web"}}})
+	store.Observe(Event{Action: "die", Actor: Actor{ID: "a", Attributes: attributes}})
+	store.Observe(Event{Action: "start", Actor: Actor{ID: "b", Attributes: map[string]string{"name": "api"}}})
+	store.Observe(Event{Action: "destroy", Actor: Actor{ID: "b"}})
+
+	// Changes are only written when flushed
+	if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("state written before flush: %v", err)
+	}
+	store.Flush()
+
+	loaded, err := loadStateStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.SeenAt.IsZero() {
+		t.Error("saved state has no seen_at")
+	}
+	web, ok := loaded.Containers["a"]
+	if !ok || web.Running || web.ExitCode != "1" || web.Attributes["execDuration"] != "" {
+		t.Errorf("state of web = %+v", web)
+	}
+	if _, ok := loaded.Containers["b"]; ok {
+		t.Error("destroyed container is still in the state")
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"notidock/config"
+	"notidock/notification"
+	"os"
+	"reflect"
+	"slices"
+	"time"
+)
+
+// configReloader applies a changed configuration to the running notifiers,
+// routes and throttler. It is used from the main loop only.
+type configReloader struct {
+	cfg        config.AppConfig
+	manager    *notification.Manager
+	throttler  *NotificationThrottler
+	crashLoops *crashLoopDetector
+	health     *healthTracker
+	correlator *eventCorrelator
+	// envNotifiers are the names of the notifiers configured through
+	// environment variables, which take precedence over the file
+	envNotifiers []string
+}
+
+func newConfigReloader(cfg config.AppConfig, manager *notification.Manager, throttler *NotificationThrottler, crashLoops *crashLoopDetector, health *healthTracker, correlator *eventCorrelator, envNotifiers []notification.Notifier) *configReloader {
+	r := &configReloader{cfg: cfg, manager: manager, throttler: throttler, crashLoops: crashLoops, health: health, correlator: correlator}
+	for _, n := range envNotifiers {
+		r.envNotifiers = append(r.envNotifiers, n.Name())
+	}
+	return r
+}
+
+// config returns the configuration currently in effect
+func (r *configReloader) config() config.AppConfig {
+	return r.cfg
+}
+
+// reload loads the configuration again and applies it. When the new
+// configuration is invalid nothing is changed and the error is returned.
+func (r *configReloader) reload() error {
+	loaded, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	next := loaded
+	keepStartupSettings(&next, r.cfg)
+	for _, c := range next.Diff(loaded) {
+		slog.Warn("configuration change requires a restart", "key", c.Key)
+	}
+
+	running := r.manager.Notifiers()
+	names := make([]string, 0, len(running))
+	for _, n := range running {
+		names = append(names, n.Name())
+	}
+
+	// Build the file notifiers that are new or changed before touching the
+	// manager, so a notifier that fails to build rejects the whole reload
+	previous := make(map[string]config.NotifierConfig, len(r.cfg.Notifiers))
+	for _, nc := range r.cfg.Notifiers {
+		previous[nc.Name] = nc
+	}
+	var replaced []notification.Notifier
+	for _, nc := range next.Notifiers {
+		if slices.Contains(r.envNotifiers, nc.Name) {
+			continue
+		}
+		if old, ok := previous[nc.Name]; ok && reflect.DeepEqual(old, nc) && slices.Contains(names, nc.Name) {
+			continue
+		}
+		n, err := buildNotifier(nc)
+		if err != nil {
+			return fmt.Errorf("notifier %s: %w", nc.Name, err)
+		}
+		replaced = append(replaced, notification.WithRetry(n, retryPolicy(next)))
+		if !slices.Contains(names, nc.Name) {
+			names = append(names, nc.Name)
+		}
+	}
+
+	var removed []string
+	for _, nc := range r.cfg.Notifiers {
+		if slices.Contains(r.envNotifiers, nc.Name) {
+			continue
+		}
+		if !slices.ContainsFunc(next.Notifiers, func(n config.NotifierConfig) bool { return n.Name == nc.Name }) {
+			removed = append(removed, nc.Name)
+		}
+	}
+	names = slices.DeleteFunc(names, func(name string) bool {
+		return slices.Contains(removed, name)
+	})
+
+	routes := buildRoutes(next.Routes)
+	if err := notification.ValidateRoutes(routes, names); err != nil {
+		return err
+	}
+	templates, err := notification.NewTemplates(next.Templates)
+	if err != nil {
+		return err
+	}
+
+	for _, n := range replaced {
+		r.manager.ReplaceNotifier(n)
+		slog.Info("notifier reloaded", "notifier", n.Name())
+	}
+	for _, name := range removed {
+		r.manager.RemoveNotifier(name)
+		slog.Info("notifier removed", "notifier", name)
+	}
+	if err := r.manager.SetRoutes(routes); err != nil {
+		return err
+	}
+	r.manager.SetTemplates(templates)
+	r.manager.SetTimeFormat(timeFormat(next))
+	r.throttler.SetLimits(next)
+	r.crashLoops.SetLimits(next)
+	r.health.SetLimits(next)
+	r.correlator.SetLimits(next)
+
+	for _, c := range r.cfg.Diff(next) {
+		switch c.Key {
+		case "notifiers":
+			// Logged per notifier above, without their credentials
+		case "routes":
+			slog.Info("configuration changed", "key", c.Key, "old", len(r.cfg.Routes), "new", len(next.Routes))
+		case "templates":
+			slog.Info("configuration changed", "key", c.Key, "old", len(r.cfg.Templates), "new", len(next.Templates))
+		default:
+			slog.Info("configuration changed", "key", c.Key, "old", c.Old, "new", c.New)
+		}
+	}
+	r.cfg = next
+	slog.Info("configuration reloaded")
+	return nil
+}
+
+// keepStartupSettings copies the settings that only take effect on restart
+// from the running configuration to next
+func keepStartupSettings(next *config.AppConfig, running config.AppConfig) {
+	next.DockerSocket = running.DockerSocket
+	next.QueueSize = running.QueueSize
+	next.QueueWorkers = running.QueueWorkers
+	next.QueueOverflow = running.QueueOverflow
+	next.CircuitFailureThreshold = running.CircuitFailureThreshold
+	next.CircuitCooldown = running.CircuitCooldown
+	next.RetryMaxAttempts = running.RetryMaxAttempts
+	next.RetryInitialBackoff = running.RetryInitialBackoff
+	next.RetryMaxBackoff = running.RetryMaxBackoff
+	next.RetryTimeout = running.RetryTimeout
+	next.StateDir = running.StateDir
+	next.DeadLetterInterval = running.DeadLetterInterval
+	next.DeadLetterMax = running.DeadLetterMax
+	next.Reconcile = running.Reconcile
+	next.StatusAddr = running.StatusAddr
+	next.ConfigWatchInterval = running.ConfigWatchInterval
+	next.StartupInventory = running.StartupInventory
+}
+
+// watchConfigFile checks the configuration file for changes every interval
+// and signals reload when its modification time or size changes
+func watchConfigFile(ctx context.Context, path string, interval time.Duration, reload chan<- struct{}) {
+	last, _ := os.Stat(path)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(path)
+			if err != nil {
+				slog.Warn("failed to check configuration file", "path", path, "error", err)
+				continue
+			}
+			if last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size() {
+				continue
+			}
+			last = info
+			select {
+			case reload <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
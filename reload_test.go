@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"notidock/config"
+	"notidock/notification"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestConfigReloader(t *testing.T) {
+	os.Clearenv()
+	path := filepath.Join(t.TempDir(), "notidock.yaml")
+	t.Setenv("NOTIDOCK_CONFIG_FILE", path)
+	writeFile := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeFile(`
+event_threshold: 10
+notifiers:
+  - name: alerts
+    webhook:
+      url: https://alerts.example.com
+  - name: audit
+    webhook:
+      url: https://audit.example.com
+`)
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager := setupNotificationManager(cfg, nil)
+	defer manager.Close(context.Background())
+	reloader := newConfigReloader(cfg, manager, NewNotificationThrottler(cfg), newCrashLoopDetector(cfg), newHealthTracker(cfg, nil), newEventCorrelator(cfg), nil)
+	alerts := manager.Notifiers()[0]
+
+	notifierNames := func() []string {
+		var names []string
+		for _, n := range manager.Notifiers() {
+			names = append(names, n.Name())
+		}
+		return names
+	}
+
+	t.Run("applies changes", func(t *testing.T) {
+		writeFile(`
+event_threshold: 5
+queue_size: 10
+notifiers:
+  - name: alerts
+    webhook:
+      url: https://alerts.example.com
+  - name: paging
+    webhook:
+      url: https://paging.example.com
+routes:
+  - actions: [die]
+    notifiers: [paging]
+`)
+		if err := reloader.reload(); err != nil {
+			t.Fatalf("reload() error = %v", err)
+		}
+
+		if got := notifierNames(); !slices.Equal(got, []string{"alerts", "paging"}) {
+			t.Errorf("notifiers = %v, want [alerts paging]", got)
+		}
+		if manager.Notifiers()[0] != alerts {
+			t.Error("unchanged notifier should not be rebuilt")
+		}
+		got := reloader.config()
+		if got.EventThreshold != 5 {
+			t.Errorf("EventThreshold = %d, want 5", got.EventThreshold)
+		}
+		if got.QueueSize != config.DefaultQueueSize {
+			t.Errorf("QueueSize = %d, requires a restart and should stay %d", got.QueueSize, config.DefaultQueueSize)
+		}
+		if len(got.Routes) != 1 {
+			t.Errorf("Routes = %+v, want one route", got.Routes)
+		}
+	})
+
+	t.Run("keeps configuration when invalid", func(t *testing.T) {
+		writeFile(`
+event_threshold: 1
+notifiers:
+  - name: alerts
+    webhook:
+      url: https://alerts.example.com
+routes:
+  - notifiers: [paging]
+`)
+		if err := reloader.reload(); err == nil {
+			t.Fatal("expected an error for a route to a removed notifier")
+		}
+
+		if got := notifierNames(); !slices.Equal(got, []string{"alerts", "paging"}) {
+			t.Errorf("notifiers = %v, want [alerts paging]", got)
+		}
+		if got := reloader.config().EventThreshold; got != 5 {
+			t.Errorf("EventThreshold = %d, want 5", got)
+		}
+	})
+}
+
+func TestConfigReloader_EnvNotifiersTakePrecedence(t *testing.T) {
+	os.Clearenv()
+	path := filepath.Join(t.TempDir(), "notidock.yaml")
+	t.Setenv("NOTIDOCK_CONFIG_FILE", path)
+	if err := os.WriteFile(path, []byte(`
+notifiers:
+  - name: webhook
+    webhook:
+      url: https://file.example.com
+`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	envNotifier, err := notification.NewWebhookNotifierWithOptions(notification.WebhookOptions{URL: "https://env.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	envNotifiers := []notification.Notifier{envNotifier}
+	manager := setupNotificationManager(cfg, envNotifiers)
+	defer manager.Close(context.Background())
+	reloader := newConfigReloader(cfg, manager, NewNotificationThrottler(cfg), newCrashLoopDetector(cfg), newHealthTracker(cfg, nil), newEventCorrelator(cfg), envNotifiers)
+
+	if err := os.WriteFile(path, []byte("event_threshold: 1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := reloader.reload(); err != nil {
+		t.Fatalf("reload() error = %v", err)
+	}
+	if got := manager.Notifiers(); len(got) != 1 || got[0].Name() != "webhook" {
+		t.Errorf("notifiers = %v, the environment notifier should be kept", got)
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"notidock/notification"
+	"slices"
+	"sync"
+	"time"
+)
+
+type healthResponse struct {
+	Status    string           `json:"status"`
+	Notifiers []notifierHealth `json:"notifiers"`
+}
+
+type notifierHealth struct {
+	Name      string `json:"name"`
+	Circuit   string `json:"circuit"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// startStatusServer serves health and runtime metrics on addr until ctx is done
+func startStatusServer(ctx context.Context, addr string, notificationManager *notification.Manager, results *deliveryResults) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writeMetrics(w, notificationManager.Stats())
+		results.writeMetrics(w)
+	})
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		health := buildHealth(notificationManager.Stats())
+		w.Header().Set("Content-Type", "application/json")
+		if health.Status == "unavailable" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(health)
+	})
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("status server failed", "error", err, "addr", addr)
+		}
+	}()
+}
+
+// buildHealth reports "ok" when every notifier's circuit is closed,
+// "degraded" when some are not and "unavailable" when none can deliver
+func buildHealth(stats []notification.QueueStats) healthResponse {
+	health := healthResponse{
+		Status:    "ok",
+		Notifiers: make([]notifierHealth, 0, len(stats)),
+	}
+
+	open := 0
+	for _, s := range stats {
+		h := notifierHealth{
+			Name:    s.Notifier,
+			Circuit: s.Circuit.String(),
+		}
+		if s.LastError != nil {
+			h.LastError = s.LastError.Error()
+		}
+		if s.Circuit != notification.CircuitClosed {
+			open++
+		}
+		health.Notifiers = append(health.Notifiers, h)
+	}
+
+	switch {
+	case len(stats) > 0 && open == len(stats):
+		health.Status = "unavailable"
+	case open > 0:
+		health.Status = "degraded"
+	}
+	return health
+}
+
+// writeMetrics renders the queue statistics in the Prometheus text format
+func writeMetrics(w io.Writer, stats []notification.QueueStats) {
+	metrics := []struct {
+		name  string
+		help  string
+		kind  string
+		value func(notification.QueueStats) any
+	}{
+		{"notidock_queue_depth", "Events waiting in the notifier queue.", "gauge", func(s notification.QueueStats) any { return s.Depth }},
+		{"notidock_queue_capacity", "Maximum number of events the notifier queue holds.", "gauge", func(s notification.QueueStats) any { return s.Capacity }},
+		{"notidock_notifications_delivered_total", "Notifications delivered successfully.", "counter", func(s notification.QueueStats) any { return s.Delivered }},
+		{"notidock_notifications_failed_total", "Notifications that failed to deliver.", "counter", func(s notification.QueueStats) any { return s.Failed }},
+		{"notidock_notifications_dropped_total", "Notifications dropped because the queue was full.", "counter", func(s notification.QueueStats) any { return s.Dropped }},
+		{"notidock_notifications_rejected_total", "Notifications not attempted because the circuit was open.", "counter", func(s notification.QueueStats) any { return s.Rejected }},
+		{"notidock_circuit_state", "Circuit breaker state: 0 closed, 1 half-open, 2 open.", "gauge", func(s notification.QueueStats) any { return int(s.Circuit) }},
+	}
+
+	for _, m := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+		for _, s := range stats {
+			fmt.Fprintf(w, "%s{notifier=%q} %v\n", m.name, s.Notifier, m.value(s))
+		}
+	}
+}
+
+// Reasons a notifier failed to deliver a notification
+const (
+	// failureQueue is a notification never handed to the notifier, because
+	// its queue was full or its circuit open
+	failureQueue     = "queue"
+	failureTransient = "transient"
+	failurePermanent = "permanent"
+)
+
+// deliveryResults counts the outcome of every notification per notifier,
+// as reported to the manager's OnResult handler
+type deliveryResults struct {
+	mu        sync.Mutex
+	notifiers map[string]*notifierResults
+}
+
+type notifierResults struct {
+	failures   map[string]uint64
+	attempts   uint64
+	deliveries uint64
+	duration   time.Duration
+}
+
+func newDeliveryResults() *deliveryResults {
+	return &deliveryResults{notifiers: make(map[string]*notifierResults)}
+}
+
+// handle counts the outcome of a notification for each notifier and logs
+// the notifiers that failed to deliver it
+func (d *deliveryResults) handle(result notification.SendResult) {
+	d.mu.Lock()
+	for _, res := range result.Results {
+		r, ok := d.notifiers[res.Notifier]
+		if !ok {
+			r = &notifierResults{failures: make(map[string]uint64)}
+			d.notifiers[res.Notifier] = r
+		}
+		if res.Attempts > 0 {
+			r.attempts += uint64(res.Attempts)
+			r.deliveries++
+			r.duration += res.Duration
+		}
+		switch {
+		case res.Err == nil:
+		case res.Attempts == 0:
+			r.failures[failureQueue]++
+		case notification.IsRetryable(res.Err):
+			r.failures[failureTransient]++
+		default:
+			r.failures[failurePermanent]++
+		}
+	}
+	d.mu.Unlock()
+
+	for _, f := range result.Failures() {
+		slog.Error("failed to send notification",
+			"notifier", f.Notifier,
+			"containerName", result.Event.ContainerName,
+			"action", result.Event.Action,
+			"attempts", f.Attempts,
+			"duration", f.Duration,
+			"error", f.Err,
+		)
+	}
+}
+
+// writeMetrics renders the delivery outcomes in the Prometheus text format
+func (d *deliveryResults) writeMetrics(w io.Writer) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	names := slices.Sorted(maps.Keys(d.notifiers))
+	fmt.Fprintf(w, "# HELP notidock_notification_failures_total Notifications a notifier failed to deliver, by reason: queue, transient or permanent.\n")
+	fmt.Fprintf(w, "# TYPE notidock_notification_failures_total counter\n")
+	for _, name := range names {
+		for _, reason := range []string{failureQueue, failureTransient, failurePermanent} {
+			fmt.Fprintf(w, "notidock_notification_failures_total{notifier=%q,reason=%q} %d\n", name, reason, d.notifiers[name].failures[reason])
+		}
+	}
+	fmt.Fprintf(w, "# HELP notidock_notification_attempts_total Delivery attempts, including retries.\n")
+	fmt.Fprintf(w, "# TYPE notidock_notification_attempts_total counter\n")
+	for _, name := range names {
+		fmt.Fprintf(w, "notidock_notification_attempts_total{notifier=%q} %d\n", name, d.notifiers[name].attempts)
+	}
+	fmt.Fprintf(w, "# HELP notidock_notification_duration_seconds Time taken to deliver a notification, including retries.\n")
+	fmt.Fprintf(w, "# TYPE notidock_notification_duration_seconds summary\n")
+	for _, name := range names {
+		r := d.notifiers[name]
+		fmt.Fprintf(w, "notidock_notification_duration_seconds_sum{notifier=%q} %v\n", name, r.duration.Seconds())
+		fmt.Fprintf(w, "notidock_notification_duration_seconds_count{notifier=%q} %d\n", name, r.deliveries)
+	}
+}
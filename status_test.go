@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"notidock/notification"
+	"strings"
+	"testing"
+	"time"
+)
+
+// failingNotifier fails every notification with err
+type failingNotifier struct {
+	name string
+	err  error
+}
+
+func (n *failingNotifier) Name() string { return n.name }
+
+func (n *failingNotifier) Send(context.Context, notification.Event) error { return n.err }
+
+func TestDeliveryResults(t *testing.T) {
+	results := newDeliveryResults()
+	manager := notification.NewManager(notification.ManagerOptions{},
+		&recordingNotifier{},
+		&failingNotifier{name: "webhook", err: notification.Permanent(errors.New("bad request"))},
+		notification.WithRetry(&failingNotifier{name: "slack", err: errors.New("connection reset")}, notification.RetryPolicy{
+			MaxAttempts:    2,
+			InitialBackoff: time.Millisecond,
+			MaxBackoff:     time.Millisecond,
+		}),
+	)
+	manager.OnResult(results.handle)
+	manager.Send(context.Background(), notification.Event{ContainerName: "web", Action: "die"})
+	manager.Close(context.Background())
+
+	var metrics strings.Builder
+	results.writeMetrics(&metrics)
+	for _, want := range []string{
+		`notidock_notification_failures_total{notifier="webhook",reason="permanent"} 1`,
+		`notidock_notification_failures_total{notifier="slack",reason="transient"} 1`,
+		`notidock_notification_failures_total{notifier="recorder",reason="transient"} 0`,
+		`notidock_notification_attempts_total{notifier="slack"} 2`,
+		`notidock_notification_duration_seconds_count{notifier="recorder"} 1`,
+	} {
+		if !strings.Contains(metrics.String(), want) {
+			t.Errorf("metrics missing %q:\n%s", want, metrics.String())
+		}
+	}
+}
+
+func TestDeliveryResults_QueueFailures(t *testing.T) {
+	results := newDeliveryResults()
+	results.handle(notification.SendResult{
+		Event:   notification.Event{ContainerName: "web", Action: "die"},
+		Results: []notification.NotifierResult{{Notifier: "slack", Err: notification.ErrQueueFull}},
+	})
+
+	var metrics strings.Builder
+	results.writeMetrics(&metrics)
+	if want := `notidock_notification_failures_total{notifier="slack",reason="queue"} 1`; !strings.Contains(metrics.String(), want) {
+		t.Errorf("metrics missing %q:\n%s", want, metrics.String())
+	}
+	if want := `notidock_notification_attempts_total{notifier="slack"} 0`; !strings.Contains(metrics.String(), want) {
+		t.Errorf("metrics missing %q:\n%s", want, metrics.String())
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"notidock/config"
+	"time"
+)
+
+// throttleStrategy limits how many notifications a throttle key may send.
+// Strategies are guarded by the NotificationThrottler's lock.
+type throttleStrategy interface {
+	// Allow records a notification of the key and reports whether it is
+	// within the limit, overridden by the container's labels. Otherwise it
+	// returns how long notifications should pause for the limit to recover.
+	Allow(key throttleKey, limits throttleLimits, now time.Time) (bool, time.Duration)
+	// Reset starts the key afresh once its pause ended
+	Reset(key throttleKey)
+	// Cleanup forgets the keys that are back within their limit
+	Cleanup(now time.Time)
+	// SetLimits applies new settings, keeping the state of every key
+	SetLimits(c config.AppConfig)
+	// Limit describes the limit for notices, such as "20 events in 1m0s"
+	Limit(limits throttleLimits) string
+}
+
+type eventBucket struct {
+	timestamp time.Time
+	count     int
+}
+
+// eventWindow is the events of a key within its window
+type eventWindow struct {
+	buckets  []eventBucket
+	duration time.Duration
+}
+
+// slidingWindow allows threshold events within the window, counted in
+// buckets of bucketDuration. Once exceeded it pauses for the window.
+// Containers may override the threshold and window with their labels.
+type slidingWindow struct {
+	containers     map[throttleKey]*eventWindow
+	windowDuration time.Duration
+	bucketDuration time.Duration // Fixed at 5 seconds
+	threshold      int
+	// global applies the limit to all containers together
+	global bool
+}
+
+func newSlidingWindow(c config.AppConfig, global bool) *slidingWindow {
+	w := &slidingWindow{
+		containers:     make(map[throttleKey]*eventWindow),
+		bucketDuration: 5 * time.Second, // Fixed bucket duration
+		global:         global,
+	}
+	w.SetLimits(c)
+	return w
+}
+
+func (w *slidingWindow) SetLimits(c config.AppConfig) {
+	if w.global {
+		w.windowDuration = c.GlobalWindowDuration
+		w.threshold = c.GlobalEventThreshold
+	} else {
+		w.windowDuration = c.WindowDuration
+		w.threshold = c.EventThreshold
+	}
+}
+
+// limits returns the threshold and window of a key, overridden by the
+// container's labels unless the limit is global
+func (w *slidingWindow) limits(limits throttleLimits) (int, time.Duration) {
+	if w.global {
+		return w.threshold, w.windowDuration
+	}
+	threshold := w.threshold
+	if limits.threshold != nil {
+		threshold = *limits.threshold
+	}
+	return threshold, cmp.Or(limits.window, w.windowDuration)
+}
+
+func (w *slidingWindow) Allow(key throttleKey, limits throttleLimits, now time.Time) (bool, time.Duration) {
+	threshold, windowDuration := w.limits(limits)
+	// If threshold is 0 or negative, the limit is disabled
+	if threshold <= 0 {
+		return true, 0
+	}
+	if w.global {
+		key = throttleKey{}
+	}
+
+	// Clean old buckets
+	cutoff := now.Add(-windowDuration)
+	newBuckets := make([]eventBucket, 0)
+	totalEvents := 0
+
+	var buckets []eventBucket
+	if window, ok := w.containers[key]; ok {
+		buckets = window.buckets
+	}
+	for _, bucket := range buckets {
+		if bucket.timestamp.After(cutoff) {
+			newBuckets = append(newBuckets, bucket)
+			totalEvents += bucket.count
+		}
+	}
+
+	// Find or create current bucket
+	currentBucketTime := now.Truncate(w.bucketDuration)
+	var currentBucket *eventBucket
+
+	for i := range newBuckets {
+		if newBuckets[i].timestamp.Equal(currentBucketTime) {
+			currentBucket = &newBuckets[i]
+			break
+		}
+	}
+
+	if currentBucket == nil {
+		newBuckets = append(newBuckets, eventBucket{
+			timestamp: currentBucketTime,
+			count:     0,
+		})
+		currentBucket = &newBuckets[len(newBuckets)-1]
+	}
+
+	// Increment current bucket
+	currentBucket.count++
+	totalEvents++
+	w.containers[key] = &eventWindow{buckets: newBuckets, duration: windowDuration}
+
+	// Check if we've exceeded the threshold
+	if totalEvents > threshold {
+		return false, windowDuration
+	}
+	return true, 0
+}
+
+func (w *slidingWindow) Limit(limits throttleLimits) string {
+	threshold, windowDuration := w.limits(limits)
+	if w.global {
+		return fmt.Sprintf("%d events in %s for all containers", threshold, windowDuration)
+	}
+	return fmt.Sprintf("%d events in %s", threshold, windowDuration)
+}
+
+func (w *slidingWindow) Reset(key throttleKey) {
+	if w.global {
+		key = throttleKey{}
+	}
+	delete(w.containers, key)
+}
+
+func (w *slidingWindow) Cleanup(now time.Time) {
+	for key, window := range w.containers {
+		cutoff := now.Add(-window.duration)
+		allBucketsOld := true
+		for _, bucket := range window.buckets {
+			if bucket.timestamp.After(cutoff) {
+				allBucketsOld = false
+				break
+			}
+		}
+		if allBucketsOld {
+			delete(w.containers, key)
+		}
+	}
+}
+
+// tokenBucket lets a container send burst notifications at once, refilled
+// with one every refillInterval. Once empty it pauses until it is full
+// again, so a container that keeps failing is notified in bursts at the
+// refill rate on average.
+type tokenBucket struct {
+	containers     map[throttleKey]*tokens
+	burst          int
+	refillInterval time.Duration
+}
+
+type tokens struct {
+	available float64
+	updated   time.Time
+}
+
+func newTokenBucket(c config.AppConfig) *tokenBucket {
+	b := &tokenBucket{containers: make(map[throttleKey]*tokens)}
+	b.SetLimits(c)
+	return b
+}
+
+func (b *tokenBucket) SetLimits(c config.AppConfig) {
+	b.burst = c.ThrottleBurst
+	b.refillInterval = c.ThrottleRefillInterval
+}
+
+func (b *tokenBucket) Allow(key throttleKey, _ throttleLimits, now time.Time) (bool, time.Duration) {
+	t := b.refill(key, now)
+	if t.available >= 1 {
+		t.available--
+		return true, 0
+	}
+	missing := float64(b.burst) - t.available
+	return false, time.Duration(missing * float64(b.refillInterval))
+}
+
+// refill adds the tokens earned since the container's last notification
+func (b *tokenBucket) refill(key throttleKey, now time.Time) *tokens {
+	t, ok := b.containers[key]
+	if !ok {
+		t = &tokens{available: float64(b.burst), updated: now}
+		b.containers[key] = t
+	}
+	if elapsed := now.Sub(t.updated); elapsed > 0 {
+		t.available = min(float64(b.burst), t.available+float64(elapsed)/float64(b.refillInterval))
+		t.updated = now
+	}
+	return t
+}
+
+func (b *tokenBucket) Limit(throttleLimits) string {
+	return fmt.Sprintf("bursts of %d events, refilled one every %s", b.burst, b.refillInterval)
+}
+
+func (b *tokenBucket) Reset(key throttleKey) {
+	delete(b.containers, key)
+}
+
+func (b *tokenBucket) Cleanup(now time.Time) {
+	for key := range b.containers {
+		if b.refill(key, now).available >= float64(b.burst) {
+			delete(b.containers, key)
+		}
+	}
+}
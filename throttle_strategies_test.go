@@ -0,0 +1,248 @@
+package main
+
+import (
+	"notidock/config"
+	"notidock/notification"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	b := newTokenBucket(config.AppConfig{ThrottleBurst: 3, ThrottleRefillInterval: 10 * time.Second})
+	key := throttleKey{container: "web"}
+	at := time.Date(2024, 12, 14, 17, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := b.Allow(key, throttleLimits{}, at); !ok {
+			t.Fatalf("notification %d of the burst denied", i+1)
+		}
+	}
+	ok, retryAfter := b.Allow(key, throttleLimits{}, at)
+	if ok {
+		t.Fatal("notification after the burst allowed")
+	}
+	if retryAfter != 30*time.Second {
+		t.Errorf("retry after %s, want the time to refill the bucket", retryAfter)
+	}
+
+	// One token is earned every refill interval
+	if ok, _ := b.Allow(key, throttleLimits{}, at.Add(10*time.Second)); !ok {
+		t.Error("notification after a refill denied")
+	}
+	if ok, _ := b.Allow(key, throttleLimits{}, at.Add(10*time.Second)); ok {
+		t.Error("second notification after a single refill allowed")
+	}
+	if ok, _ := b.Allow(throttleKey{container: "api"}, throttleLimits{}, at); !ok {
+		t.Error("other container limited by the bucket of web")
+	}
+
+	b.Cleanup(at.Add(time.Hour))
+	if len(b.containers) != 0 {
+		t.Errorf("bucket still tracks %d containers", len(b.containers))
+	}
+}
+
+func TestNotificationThrottler_TokenBucket(t *testing.T) {
+	throttler := NewNotificationThrottler(config.AppConfig{
+		ThrottleStrategies:     []string{config.ThrottleTokenBucket},
+		ThrottleBurst:          2,
+		ThrottleRefillInterval: time.Minute,
+		PauseUntilRecovered:    true,
+	})
+	at := time.Now()
+	event := notification.Event{ContainerName: "worker", Action: "die", ExitStatus: "1"}
+
+	throttler.Check(event, at)
+	throttler.Check(event, at)
+	notices, notify := throttler.Check(event, at)
+	if notify || len(notices) != 1 {
+		t.Fatalf("Check() over the burst = %+v, %v, want the throttled notice", notices, notify)
+	}
+	if got, want := notices[0].Labels["limit"], "bursts of 2 events, refilled one every 1m0s"; got != want {
+		t.Errorf("limit = %q, want %q", got, want)
+	}
+	if got := notices[0].Labels["paused_for"]; got != "2m0s" {
+		t.Errorf("paused_for = %q, want the time to refill the bucket", got)
+	}
+
+	summaries := throttler.Resume(at.Add(2 * time.Minute))
+	if len(summaries) != 1 || summaries[0].Labels["suppressed"] != "1" {
+		t.Fatalf("Resume() = %+v, want the summary of one suppressed event", summaries)
+	}
+	if _, notify := throttler.Check(event, at.Add(2*time.Minute)); !notify {
+		t.Error("notification after the pause denied")
+	}
+}
+
+func TestNotificationThrottler_Global(t *testing.T) {
+	throttler := NewNotificationThrottler(config.AppConfig{
+		ThrottleStrategies:   []string{config.ThrottleSlidingWindow, config.ThrottleGlobal},
+		WindowDuration:       time.Minute,
+		EventThreshold:       5,
+		GlobalWindowDuration: time.Minute,
+		GlobalEventThreshold: 3,
+		PauseUntilRecovered:  true,
+	})
+	at := time.Date(2024, 12, 14, 17, 0, 0, 0, time.UTC)
+
+	// A host reboot: every container dies at once
+	var notified, paused int
+	for _, name := range []string{"api", "db", "cache", "worker", "web", "proxy"} {
+		event := notification.Event{
+			ContainerName: name,
+			Action:        "die",
+			Labels:        map[string]string{"image": name + ":1"},
+			Target:        &notification.Target{SlackChannel: "#" + name},
+		}
+		notices, notify := throttler.Check(event, at)
+		if notify {
+			notified++
+		}
+		for _, notice := range notices {
+			paused++
+			if notice.ContainerName != allContainers || notice.Target != nil || notice.Labels["image"] != "" {
+				t.Errorf("global notice = %+v, want it for all containers", notice)
+			}
+		}
+	}
+	if notified != 3 || paused != 1 {
+		t.Fatalf("notified %d events and %d pauses, want 3 and 1", notified, paused)
+	}
+
+	summaries := throttler.Resume(at.Add(time.Minute))
+	if len(summaries) != 1 {
+		t.Fatalf("Resume() returned %d summaries, want 1", len(summaries))
+	}
+	labels := summaries[0].Labels
+	if labels["suppressed"] != "3" || labels["suppressed_containers"] != "proxy×1, web×1, worker×1" {
+		t.Errorf("summary labels = %v", labels)
+	}
+	if _, notify := throttler.Check(notification.Event{ContainerName: "api"}, at.Add(time.Minute)); !notify {
+		t.Error("notification after the global pause denied")
+	}
+}
+
+func TestNotificationThrottler_SetLimitsKeepsState(t *testing.T) {
+	cfg := config.AppConfig{WindowDuration: time.Minute, EventThreshold: 2}
+	throttler := NewNotificationThrottler(cfg)
+	at := time.Now()
+	event := notification.Event{ContainerName: "web"}
+	throttler.Check(event, at)
+	throttler.Check(event, at)
+
+	cfg.ThrottleStrategies = []string{config.ThrottleSlidingWindow, config.ThrottleTokenBucket}
+	cfg.ThrottleBurst = 10
+	cfg.ThrottleRefillInterval = time.Second
+	throttler.SetLimits(cfg)
+
+	if _, notify := throttler.Check(event, at); notify {
+		t.Error("reload forgot the events within the window")
+	}
+}
+
+func TestNotificationThrottler_Key(t *testing.T) {
+	throttler := NewNotificationThrottler(config.AppConfig{
+		ThrottleKey:    []string{config.ThrottleKeyProject, config.ThrottleKeyService},
+		WindowDuration: time.Minute,
+		EventThreshold: 2,
+	})
+	at := time.Now()
+	replica := func(name, image string) notification.Event {
+		return notification.Event{ContainerName: name, Action: "die", Labels: map[string]string{
+			"image":             image,
+			composeProjectLabel: "shop",
+			composeServiceLabel: "payments",
+		}}
+	}
+
+	// Replicas share their limit, across deploys of a new tag
+	throttler.Check(replica("shop-payments-1", "payments:1"), at)
+	throttler.Check(replica("shop-payments-2", "payments:2"), at)
+	notices, notify := throttler.Check(replica("shop-payments-3", "payments:2"), at)
+	if notify || len(notices) != 1 || notices[0].ContainerName != "shop/payments" {
+		t.Fatalf("Check() of the third replica = %+v, %v, want the service paused", notices, notify)
+	}
+
+	// Containers not started by compose are throttled on their own
+	if _, notify := throttler.Check(notification.Event{ContainerName: "cron"}, at); !notify {
+		t.Error("container outside compose throttled with the service")
+	}
+	if _, notify := throttler.Check(notification.Event{ContainerName: "backup"}, at); !notify {
+		t.Error("containers outside compose throttled together")
+	}
+}
+
+func TestThrottleKey_Name(t *testing.T) {
+	tests := []struct {
+		key  throttleKey
+		want string
+	}{
+		{throttleKey{container: "web", image: "nginx:1"}, "web"},
+		{throttleKey{project: "shop", service: "payments"}, "shop/payments"},
+		{throttleKey{service: "payments", action: "die"}, "payments (die)"},
+		{throttleKey{image: "nginx:1"}, "nginx:1"},
+		{throttleKey{action: "oom"}, "oom"},
+	}
+	for _, tt := range tests {
+		if got := tt.key.name(); got != tt.want {
+			t.Errorf("%+v.name() = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestNotificationThrottler_LabelOverrides(t *testing.T) {
+	throttler := NewNotificationThrottler(config.AppConfig{
+		WindowDuration:       time.Minute,
+		EventThreshold:       5,
+		NotificationCooldown: time.Minute,
+	})
+	at := time.Now()
+	batch := notification.Event{ContainerName: "batch", Labels: map[string]string{
+		LabelThrottleThreshold: "1",
+		LabelThrottleWindow:    "1h",
+		LabelThrottleCooldown:  "2h",
+	}}
+	critical := notification.Event{ContainerName: "db", Labels: map[string]string{
+		LabelThrottleThreshold: "0",
+	}}
+
+	throttler.Check(batch, at)
+	notices, notify := throttler.Check(batch, at.Add(30*time.Minute))
+	if notify || len(notices) != 1 {
+		t.Fatalf("Check() over the label threshold = %+v, %v, want the throttled notice", notices, notify)
+	}
+	if got := notices[0].Labels["limit"]; got != "1 events in 1h0m0s" {
+		t.Errorf("limit = %q, want the limit of the labels", got)
+	}
+	if got := notices[0].Labels["paused_for"]; got != "2h0m0s" {
+		t.Errorf("paused_for = %q, want the cooldown of the label", got)
+	}
+
+	// A threshold of 0 never throttles the container
+	for i := 0; i < 10; i++ {
+		if _, notify := throttler.Check(critical, at); !notify {
+			t.Fatalf("notification %d of a container with threshold 0 denied", i+1)
+		}
+	}
+
+	if _, ok := throttler.window.containers[throttleKey{container: "db"}]; ok {
+		t.Error("container without a limit tracked")
+	}
+
+	// Cleanup keeps the events within the window of the label
+	throttler.window.Cleanup(at.Add(45 * time.Minute))
+	if _, ok := throttler.window.containers[throttleKey{container: "batch"}]; !ok {
+		t.Error("cleanup forgot the events within the window of the label")
+	}
+}
+
+func TestGetThrottleLimits_Invalid(t *testing.T) {
+	limits := getThrottleLimits(map[string]string{
+		LabelThrottleThreshold: "-1",
+		LabelThrottleWindow:    "soon",
+		LabelThrottleCooldown:  "0s",
+	})
+	if limits != (throttleLimits{}) {
+		t.Errorf("getThrottleLimits() = %+v, want no overrides", limits)
+	}
+}
@@ -2,157 +2,413 @@
 package main
 
 import (
+	"cmp"
+	"context"
+	"maps"
 	"notidock/config"
+	"notidock/notification"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
 
-type containerKey struct {
-	name     string
-	imageTag string
+// Labels of the containers started by docker compose
+const (
+	composeServiceLabel = "com.docker.compose.service"
+	composeProjectLabel = "com.docker.compose.project"
+)
+
+// Labels overriding the throttling settings of a container
+const (
+	LabelThrottleThreshold = LabelPrefix + "throttle.threshold"
+	LabelThrottleWindow    = LabelPrefix + "throttle.window"
+	LabelThrottleCooldown  = LabelPrefix + "throttle.cooldown"
+)
+
+// throttleKey identifies the events that share their limits, by the parts
+// selected with THROTTLE_KEY. The parts not selected are empty.
+type throttleKey struct {
+	container string
+	image     string
+	service   string
+	project   string
+	action    string
+}
+
+// name names the events of the key in notices, such as "web",
+// "shop/payments" for a compose service, or "web (die)"
+func (k throttleKey) name() string {
+	name := k.container
+	if name == "" && k.project != "" && k.service != "" {
+		name = k.project + "/" + k.service
+	}
+	name = cmp.Or(name, k.service, k.project, k.image)
+	switch {
+	case k.action == "":
+		return name
+	case name == "":
+		return k.action
+	default:
+		return name + " (" + k.action + ")"
+	}
+}
+
+// throttleLimits are the throttling settings a container overrides with its
+// labels. Nil and zero values keep the configured settings.
+type throttleLimits struct {
+	threshold *int
+	window    time.Duration
+	cooldown  time.Duration
 }
 
-type eventBucket struct {
-	timestamp time.Time
-	count     int
+// getThrottleLimits reads the throttling overrides of a container from its
+// labels. Invalid values are ignored.
+func getThrottleLimits(labels map[string]string) throttleLimits {
+	var limits throttleLimits
+	if threshold, err := strconv.Atoi(labels[LabelThrottleThreshold]); err == nil && threshold >= 0 {
+		limits.threshold = &threshold
+	}
+	if window, err := time.ParseDuration(labels[LabelThrottleWindow]); err == nil && window > 0 {
+		limits.window = window
+	}
+	if cooldown, err := time.ParseDuration(labels[LabelThrottleCooldown]); err == nil && cooldown > 0 {
+		limits.cooldown = cooldown
+	}
+	return limits
 }
 
+// allContainers names the pause of the global limit in its notices
+const allContainers = "all containers"
+
+// throttleState is a pause of notifications, and what the notifications it
+// suppressed were about
 type throttleState struct {
-	buckets        []eventBucket
-	suspended      bool
-	suspendedAt    time.Time
-	bucketDuration time.Duration
+	suspendedAt time.Time
+	pausedFor   time.Duration
+	// labels and target are those of the event that started the pause, so
+	// its notices are routed like the container's events
+	labels     map[string]string
+	target     *notification.Target
+	suppressed int
+	actions    map[string]int
+	exitCodes  map[string]int
+	// containers counts the notifications of each container suppressed by
+	// the global pause
+	containers map[string]int
 }
 
+// NotificationThrottler pauses the notifications of a throttle key once
+// they exceed the limits of the selected strategies, or of every container
+// once they exceed the global limit together
 type NotificationThrottler struct {
-	mu              sync.RWMutex
-	state           map[containerKey]*throttleState
-	windowDuration  time.Duration
-	bucketDuration  time.Duration // Fixed at 5 seconds
-	threshold       int
+	mu sync.RWMutex
+	// state holds the keys whose notifications are paused
+	state map[throttleKey]*throttleState
+	// globalPause pauses the notifications of every container
+	globalPause *throttleState
+
+	// strategies are the selected limits of each container, and global the
+	// limit of all of them, nil unless selected
+	strategies []throttleStrategy
+	global     throttleStrategy
+	keyParts   []string
+	// Every strategy is kept, so a reload selecting it again keeps its state
+	window          *slidingWindow
+	tokens          *tokenBucket
+	globalWindow    *slidingWindow
 	cooldownPeriod  time.Duration
+	untilRecovered  bool
 	cleanupInterval time.Duration
 }
 
 func NewNotificationThrottler(c config.AppConfig) *NotificationThrottler {
-	return &NotificationThrottler{
-		state:           make(map[containerKey]*throttleState),
-		windowDuration:  c.WindowDuration,
-		bucketDuration:  5 * time.Second, // Fixed bucket duration
-		threshold:       c.EventThreshold,
-		cooldownPeriod:  c.NotificationCooldown,
+	nt := &NotificationThrottler{
+		state:           make(map[throttleKey]*throttleState),
+		window:          newSlidingWindow(c, false),
+		tokens:          newTokenBucket(c),
+		globalWindow:    newSlidingWindow(c, true),
 		cleanupInterval: 1 * time.Hour,
 	}
+	nt.SetLimits(c)
+	return nt
 }
 
-func (nt *NotificationThrottler) ShouldNotify(containerName, imageTag string) bool {
-	// If threshold is 0 or negative, throttling is disabled
-	if nt.threshold <= 0 {
-		return true
+// SetLimits applies new throttling settings, keeping the state of every
+// container
+func (nt *NotificationThrottler) SetLimits(c config.AppConfig) {
+	nt.mu.Lock()
+	defer nt.mu.Unlock()
+
+	nt.cooldownPeriod = c.NotificationCooldown
+	nt.untilRecovered = c.PauseUntilRecovered
+	nt.window.SetLimits(c)
+	nt.tokens.SetLimits(c)
+	nt.globalWindow.SetLimits(c)
+
+	nt.keyParts = c.ThrottleKey
+	if len(nt.keyParts) == 0 {
+		nt.keyParts = []string{config.ThrottleKeyContainer, config.ThrottleKeyImage}
+	}
+	names := c.ThrottleStrategies
+	if len(names) == 0 {
+		names = []string{config.ThrottleSlidingWindow}
 	}
+	nt.strategies = nil
+	nt.global = nil
+	for _, name := range names {
+		switch name {
+		case config.ThrottleSlidingWindow:
+			nt.strategies = append(nt.strategies, nt.window)
+		case config.ThrottleTokenBucket:
+			nt.strategies = append(nt.strategies, nt.tokens)
+		case config.ThrottleGlobal:
+			nt.global = nt.globalWindow
+		}
+	}
+}
 
-	key := containerKey{name: containerName, imageTag: imageTag}
-	now := time.Now()
+// Check reports whether the notification may be sent, and returns the
+// notices to send first: the summaries of the pauses that ended, and the
+// notice that notifications are paused when this event exceeds a limit
+func (nt *NotificationThrottler) Check(event notification.Event, now time.Time) ([]notification.Event, bool) {
+	limits := getThrottleLimits(event.Labels)
 
 	nt.mu.Lock()
 	defer nt.mu.Unlock()
 
-	state, exists := nt.state[key]
-	if !exists {
-		// Initialize new state with empty buckets
-		state = &throttleState{
-			buckets:        make([]eventBucket, 0),
-			bucketDuration: nt.bucketDuration,
-		}
-		nt.state[key] = state
+	key := nt.key(event)
+
+	// Pauses end with the next event when Resume did not run yet
+	var notices []notification.Event
+	if state, ok := nt.state[key]; ok && nt.pauseOver(state, now) {
+		notices = append(notices, nt.resume(key, now))
+	}
+	if nt.globalPause != nil && nt.pauseOver(nt.globalPause, now) {
+		notices = append(notices, nt.resumeGlobal(now))
 	}
 
-	// If we're in suspended state, check cooldown
-	if state.suspended {
-		if now.Sub(state.suspendedAt) >= nt.cooldownPeriod {
-			state.suspended = false
-			state.buckets = make([]eventBucket, 0) // Reset buckets after cooldown
-		} else {
-			return false
+	if state, ok := nt.state[key]; ok {
+		state.record(event)
+		return notices, false
+	}
+	if nt.globalPause != nil {
+		nt.globalPause.record(event)
+		return notices, false
+	}
+
+	for _, strategy := range nt.strategies {
+		if ok, retryAfter := strategy.Allow(key, limits, now); !ok {
+			state := nt.pause(event, retryAfter, limits.cooldown, now)
+			nt.state[key] = state
+			return append(notices, pausedNotice(key.name(), strategy.Limit(limits), state, now)), false
+		}
+	}
+	if nt.global != nil {
+		if ok, retryAfter := nt.global.Allow(key, throttleLimits{}, now); !ok {
+			nt.globalPause = nt.pause(event, retryAfter, 0, now)
+			// Not routed like the container that happened to exceed it
+			nt.globalPause.labels = map[string]string{"scope": config.ThrottleGlobal}
+			nt.globalPause.target = nil
+			return append(notices, pausedNotice(allContainers, nt.global.Limit(throttleLimits{}), nt.globalPause, now)), false
 		}
 	}
 
-	// Clean old buckets
-	cutoff := now.Add(-nt.windowDuration)
-	newBuckets := make([]eventBucket, 0)
-	totalEvents := 0
+	return notices, true
+}
 
-	for _, bucket := range state.buckets {
-		if bucket.timestamp.After(cutoff) {
-			newBuckets = append(newBuckets, bucket)
-			totalEvents += bucket.count
+// key returns the throttle key of the event. Containers not started by
+// compose are keyed by their name in place of the compose service or
+// project, so they are not throttled together.
+func (nt *NotificationThrottler) key(event notification.Event) throttleKey {
+	var key throttleKey
+	for _, part := range nt.keyParts {
+		switch part {
+		case config.ThrottleKeyContainer:
+			key.container = event.ContainerName
+		case config.ThrottleKeyImage:
+			key.image = event.Labels["image"]
+		case config.ThrottleKeyService:
+			key.service = event.Labels[composeServiceLabel]
+			if key.service == "" {
+				key.container = event.ContainerName
+			}
+		case config.ThrottleKeyProject:
+			key.project = event.Labels[composeProjectLabel]
+			if key.project == "" {
+				key.container = event.ContainerName
+			}
+		case config.ThrottleKeyAction:
+			key.action = event.Action
 		}
 	}
-	state.buckets = newBuckets
+	return key
+}
 
-	// Find or create current bucket
-	currentBucketTime := now.Truncate(nt.bucketDuration)
-	var currentBucket *eventBucket
+// Resume ends the pauses that are over and returns their summaries
+func (nt *NotificationThrottler) Resume(now time.Time) []notification.Event {
+	nt.mu.Lock()
+	defer nt.mu.Unlock()
 
-	for i := range state.buckets {
-		if state.buckets[i].timestamp.Equal(currentBucketTime) {
-			currentBucket = &state.buckets[i]
-			break
+	var summaries []notification.Event
+	for key, state := range nt.state {
+		if nt.pauseOver(state, now) {
+			summaries = append(summaries, nt.resume(key, now))
 		}
 	}
+	if nt.globalPause != nil && nt.pauseOver(nt.globalPause, now) {
+		summaries = append(summaries, nt.resumeGlobal(now))
+	}
+	slices.SortFunc(summaries, func(a, b notification.Event) int {
+		return strings.Compare(a.ContainerName, b.ContainerName)
+	})
+	return summaries
+}
+
+func (nt *NotificationThrottler) pauseOver(state *throttleState, now time.Time) bool {
+	return now.Sub(state.suspendedAt) >= state.pausedFor
+}
+
+// pause starts a pause of the cooldown of the container, else the
+// configured one. Without one the pause ends with the next event, or lasts
+// the time the exceeded limit needs to recover if configured so
+func (nt *NotificationThrottler) pause(event notification.Event, retryAfter, cooldown time.Duration, now time.Time) *throttleState {
+	labels := maps.Clone(event.Labels)
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	state := &throttleState{
+		suspendedAt: now,
+		labels:      labels,
+		target:      event.Target,
+		actions:     make(map[string]int),
+		exitCodes:   make(map[string]int),
+		containers:  make(map[string]int),
+	}
+	if cooldown := cmp.Or(cooldown, nt.cooldownPeriod); cooldown > 0 {
+		state.pausedFor = cooldown
+	} else if nt.untilRecovered {
+		state.pausedFor = retryAfter
+	}
+	state.record(event)
+	return state
+}
+
+// resume ends the pause of the key, starting its limits afresh, and
+// returns the summary of the notifications it suppressed
+func (nt *NotificationThrottler) resume(key throttleKey, now time.Time) notification.Event {
+	state := nt.state[key]
+	delete(nt.state, key)
+	for _, strategy := range nt.strategies {
+		strategy.Reset(key)
+	}
+	return state.summary(key.name(), now)
+}
+
+// resumeGlobal ends the pause of every container
+func (nt *NotificationThrottler) resumeGlobal(now time.Time) notification.Event {
+	state := nt.globalPause
+	nt.globalPause = nil
+	nt.globalWindow.Reset(throttleKey{})
+	summary := state.summary(allContainers, now)
+	summary.Labels["suppressed_containers"] = formatCounts(state.containers, "×", true)
+	return summary
+}
 
-	if currentBucket == nil {
-		state.buckets = append(state.buckets, eventBucket{
-			timestamp: currentBucketTime,
-			count:     0,
-		})
-		currentBucket = &state.buckets[len(state.buckets)-1]
+// pausedNotice tells that notifications are paused, leaving out paused_for
+// when the pause ends with the next event
+func pausedNotice(containerName, limit string, state *throttleState, now time.Time) notification.Event {
+	labels := maps.Clone(state.labels)
+	labels["limit"] = limit
+	if state.pausedFor > 0 {
+		labels["paused_for"] = state.pausedFor.String()
 	}
+	return notification.Event{
+		ContainerName: containerName,
+		Action:        "throttled",
+		Timestamp:     now.UTC(),
+		Labels:        labels,
+		Target:        state.target,
+	}
+}
 
-	// Increment current bucket
-	currentBucket.count++
-	totalEvents++
+// summary returns the summary of the notifications the pause suppressed.
+// paused_for is how long it actually lasted, as a pause without a length
+// ends with whichever comes first of the next event and Resume.
+func (s *throttleState) summary(containerName string, now time.Time) notification.Event {
+	labels := maps.Clone(s.labels)
+	labels["paused_for"] = now.Sub(s.suspendedAt).Truncate(time.Second).String()
+	labels["suppressed"] = strconv.Itoa(s.suppressed)
+	labels["suppressed_actions"] = formatCounts(s.actions, " ", false)
+	if len(s.exitCodes) > 0 {
+		labels["suppressed_exit_codes"] = formatCounts(s.exitCodes, "×", true)
+	}
+	return notification.Event{
+		ContainerName: containerName,
+		Action:        "throttle_summary",
+		Timestamp:     now.UTC(),
+		Labels:        labels,
+		Target:        s.target,
+	}
+}
 
-	// Check if we've exceeded the threshold
-	if totalEvents > nt.threshold {
-		state.suspended = true
-		state.suspendedAt = now
-		return false
+// record counts a suppressed notification
+func (s *throttleState) record(event notification.Event) {
+	s.suppressed++
+	s.actions[event.Action]++
+	if event.ExitStatus != "" && event.ExitStatus != "0" {
+		s.exitCodes[event.ExitStatus]++
 	}
+	s.containers[event.ContainerName]++
+}
 
-	return true
+// formatCounts lists counts from the most frequent, such as "18 die, 1 oom",
+// or with countLast "1×17, 137×1"
+func formatCounts(counts map[string]int, sep string, countLast bool) string {
+	names := slices.Collect(maps.Keys(counts))
+	slices.SortFunc(names, func(a, b string) int {
+		if c := counts[b] - counts[a]; c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+	parts := make([]string, len(names))
+	for i, name := range names {
+		if countLast {
+			parts[i] = name + sep + strconv.Itoa(counts[name])
+		} else {
+			parts[i] = strconv.Itoa(counts[name]) + sep + name
+		}
+	}
+	return strings.Join(parts, ", ")
 }
 
-func (nt *NotificationThrottler) periodicCleanup() {
+// periodicCleanup forgets the throttle keys that are back within their
+// limits until ctx is done, so removed containers do not accumulate
+func (nt *NotificationThrottler) periodicCleanup(ctx context.Context) {
 	ticker := time.NewTicker(nt.cleanupInterval)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		nt.cleanup()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			nt.cleanup()
+		}
 	}
 }
 
+// cleanup prunes the state of every strategy, including those not
+// selected, which keep their state for a reload selecting them again
 func (nt *NotificationThrottler) cleanup() {
 	nt.mu.Lock()
 	defer nt.mu.Unlock()
 
+	// Paused containers are forgotten when their pause ends
 	now := time.Now()
-	cutoff := now.Add(-nt.windowDuration)
-
-	for key, state := range nt.state {
-		// Remove entries where:
-		// 1. All buckets are old (outside window duration)
-		// 2. Not in suspended state OR suspended state has expired
-		allBucketsOld := true
-		for _, bucket := range state.buckets {
-			if bucket.timestamp.After(cutoff) {
-				allBucketsOld = false
-				break
-			}
-		}
-
-		if allBucketsOld && (!state.suspended || now.Sub(state.suspendedAt) > nt.cooldownPeriod) {
-			delete(nt.state, key)
-		}
-	}
+	nt.window.Cleanup(now)
+	nt.tokens.Cleanup(now)
+	nt.globalWindow.Cleanup(now)
 }
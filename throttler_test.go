@@ -1,12 +1,21 @@
 package main
 
 import (
+	"context"
 	"notidock/config"
+	"notidock/notification"
 	"os"
 	"testing"
 	"time"
 )
 
+// shouldNotify checks an event of the container happening now
+func shouldNotify(throttler *NotificationThrottler, containerName, imageTag string) bool {
+	event := notification.Event{ContainerName: containerName, Labels: map[string]string{"image": imageTag}}
+	_, notify := throttler.Check(event, time.Now())
+	return notify
+}
+
 func TestNotificationThrottler(t *testing.T) {
 	t.Run("test throttling disabled with zero threshold", func(t *testing.T) {
 		os.Clearenv()
@@ -20,7 +29,7 @@ func TestNotificationThrottler(t *testing.T) {
 
 		// Should always allow notifications when threshold is 0
 		for i := 0; i < 5; i++ {
-			if !throttler.ShouldNotify("container1", "image:1.0") {
+			if !shouldNotify(throttler, "container1", "image:1.0") {
 				t.Error("Expected notification to be allowed when throttling is disabled")
 			}
 		}
@@ -39,18 +48,18 @@ func TestNotificationThrottler(t *testing.T) {
 
 		// First three notifications should go through
 		for i := 0; i < 3; i++ {
-			if !throttler.ShouldNotify("container1", "image:1.0") {
+			if !shouldNotify(throttler, "container1", "image:1.0") {
 				t.Errorf("Notification %d should be allowed", i+1)
 			}
 		}
 
 		// Fourth notification should be blocked
-		if throttler.ShouldNotify("container1", "image:1.0") {
+		if shouldNotify(throttler, "container1", "image:1.0") {
 			t.Error("Fourth notification should be blocked")
 		}
 
 		// Different container/image combination should be allowed
-		if !throttler.ShouldNotify("container2", "image:2.0") {
+		if !shouldNotify(throttler, "container2", "image:2.0") {
 			t.Error("Different container/image combination should be allowed")
 		}
 	})
@@ -71,7 +80,7 @@ func TestNotificationThrottler(t *testing.T) {
 
 		// Send 2 events
 		for i := 0; i < 2; i++ {
-			if !throttler.ShouldNotify("container1", "image:1.0") {
+			if !shouldNotify(throttler, "container1", "image:1.0") {
 				t.Errorf("Notification %d should be allowed", i+1)
 			}
 		}
@@ -81,13 +90,13 @@ func TestNotificationThrottler(t *testing.T) {
 
 		// Should be allowed to send 3 more events as old ones expired
 		for i := 0; i < 3; i++ {
-			if !throttler.ShouldNotify("container1", "image:1.0") {
+			if !shouldNotify(throttler, "container1", "image:1.0") {
 				t.Errorf("Notification %d should be allowed after window reset", i+1)
 			}
 		}
 
 		// Fourth should be blocked
-		if throttler.ShouldNotify("container1", "image:1.0") {
+		if shouldNotify(throttler, "container1", "image:1.0") {
 			t.Error("Fourth notification should be blocked")
 		}
 	})
@@ -105,11 +114,11 @@ func TestNotificationThrottler(t *testing.T) {
 
 		// Send events until throttled
 		for i := 0; i < 3; i++ {
-			throttler.ShouldNotify("container1", "image:1.0")
+			shouldNotify(throttler, "container1", "image:1.0")
 		}
 
 		// Should be blocked during cooldown
-		if throttler.ShouldNotify("container1", "image:1.0") {
+		if shouldNotify(throttler, "container1", "image:1.0") {
 			t.Error("Should be blocked during cooldown")
 		}
 
@@ -117,7 +126,7 @@ func TestNotificationThrottler(t *testing.T) {
 		time.Sleep(2100 * time.Millisecond)
 
 		// Should be allowed again
-		if !throttler.ShouldNotify("container1", "image:1.0") {
+		if !shouldNotify(throttler, "container1", "image:1.0") {
 			t.Error("Should be allowed after cooldown")
 		}
 	})
@@ -135,7 +144,7 @@ func TestNotificationThrottler(t *testing.T) {
 
 		// Send 2 events
 		for i := 0; i < 2; i++ {
-			if !throttler.ShouldNotify("container1", "image:1.0") {
+			if !shouldNotify(throttler, "container1", "image:1.0") {
 				t.Error("Initial notifications should be allowed")
 			}
 		}
@@ -144,12 +153,12 @@ func TestNotificationThrottler(t *testing.T) {
 		time.Sleep(5100 * time.Millisecond)
 
 		// Send 1 more event (should still be within threshold)
-		if !throttler.ShouldNotify("container1", "image:1.0") {
+		if !shouldNotify(throttler, "container1", "image:1.0") {
 			t.Error("Should be allowed as within total threshold")
 		}
 
 		// Send 1 more event (should be blocked as it exceeds threshold)
-		if throttler.ShouldNotify("container1", "image:1.0") {
+		if shouldNotify(throttler, "container1", "image:1.0") {
 			t.Error("Should be blocked as it exceeds threshold")
 		}
 	})
@@ -166,8 +175,8 @@ func TestNotificationThrottler(t *testing.T) {
 		throttler := NewNotificationThrottler(cfg)
 
 		// Add some entries
-		throttler.ShouldNotify("container1", "image:1.0")
-		throttler.ShouldNotify("container2", "image:2.0")
+		shouldNotify(throttler, "container1", "image:1.0")
+		shouldNotify(throttler, "container2", "image:2.0")
 
 		// Wait for more than window duration + 2*cooldown
 		time.Sleep(7 * time.Second)
@@ -177,7 +186,7 @@ func TestNotificationThrottler(t *testing.T) {
 
 		// Check internal state
 		throttler.mu.RLock()
-		stateSize := len(throttler.state)
+		stateSize := len(throttler.state) + len(throttler.window.containers)
 		throttler.mu.RUnlock()
 
 		if stateSize != 0 {
@@ -185,3 +194,168 @@ func TestNotificationThrottler(t *testing.T) {
 		}
 	})
 }
+
+func TestNotificationThrottler_PeriodicCleanup(t *testing.T) {
+	throttler := NewNotificationThrottler(config.AppConfig{
+		WindowDuration:         50 * time.Millisecond,
+		EventThreshold:         5,
+		ThrottleStrategies:     []string{config.ThrottleSlidingWindow, config.ThrottleTokenBucket},
+		ThrottleBurst:          5,
+		ThrottleRefillInterval: 10 * time.Millisecond,
+	})
+	throttler.cleanupInterval = 20 * time.Millisecond
+	shouldNotify(throttler, "removed", "image:1.0")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		throttler.periodicCleanup(ctx)
+		close(done)
+	}()
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+	<-done
+
+	throttler.mu.RLock()
+	defer throttler.mu.RUnlock()
+	if n := len(throttler.window.containers) + len(throttler.tokens.containers); n != 0 {
+		t.Errorf("expected the strategies of the removed container to be pruned, got %d entries", n)
+	}
+}
+
+func TestNotificationThrottler_Summary(t *testing.T) {
+	throttler := NewNotificationThrottler(config.AppConfig{
+		WindowDuration:      5 * time.Minute,
+		EventThreshold:      2,
+		PauseUntilRecovered: true,
+	})
+	at := time.Date(2024, 12, 14, 17, 0, 0, 0, time.UTC)
+	event := func(action, exitStatus string) notification.Event {
+		return notification.Event{
+			ContainerName: "payments-api",
+			Action:        action,
+			Labels:        map[string]string{"image": "payments:1.2"},
+			ExitStatus:    exitStatus,
+		}
+	}
+
+	for _, e := range []notification.Event{event("start", ""), event("die", "1")} {
+		if notices, notify := throttler.Check(e, at); !notify || len(notices) != 0 {
+			t.Fatalf("Check(%s) = %v, %v, want it notified", e.Action, notices, notify)
+		}
+	}
+	notices, notify := throttler.Check(event("die", "1"), at)
+	if notify || len(notices) != 1 || notices[0].Action != "throttled" {
+		t.Fatalf("Check() over the threshold = %+v, %v, want the throttled notice", notices, notify)
+	}
+	if got := notices[0].Labels["paused_for"]; got != "5m0s" {
+		t.Errorf("paused_for = %q, want the window until it recovers", got)
+	}
+	for _, e := range []notification.Event{event("die", "1"), event("oom", ""), event("start", ""), event("die", "137")} {
+		if notices, notify := throttler.Check(e, at.Add(time.Minute)); notify || len(notices) != 0 {
+			t.Fatalf("Check(%s) while paused = %v, %v", e.Action, notices, notify)
+		}
+	}
+
+	if summaries := throttler.Resume(at.Add(4 * time.Minute)); len(summaries) != 0 {
+		t.Fatalf("resumed before the pause ended: %+v", summaries)
+	}
+	summaries := throttler.Resume(at.Add(5 * time.Minute))
+	if len(summaries) != 1 {
+		t.Fatalf("Resume() returned %d summaries, want 1", len(summaries))
+	}
+	summary := summaries[0]
+	want := map[string]string{
+		"suppressed":            "5",
+		"suppressed_actions":    "3 die, 1 oom, 1 start",
+		"suppressed_exit_codes": "1×2, 137×1",
+	}
+	for label, value := range want {
+		if got := summary.Labels[label]; got != value {
+			t.Errorf("%s = %q, want %q", label, got, value)
+		}
+	}
+
+	if notices, notify := throttler.Check(event("start", ""), at.Add(6*time.Minute)); !notify || len(notices) != 0 {
+		t.Errorf("Check() after the summary = %v, %v, want it notified", notices, notify)
+	}
+}
+
+func TestNotificationThrottler_DefaultCooldown(t *testing.T) {
+	cfg := config.AppConfig{
+		WindowDuration:       config.DefaultWindowDuration,
+		EventThreshold:       config.DefaultEventThreshold,
+		NotificationCooldown: config.DefaultNotificationCooldown,
+		PauseUntilRecovered:  config.DefaultPauseUntilRecovered,
+	}
+	throttler := NewNotificationThrottler(cfg)
+	at := time.Date(2024, 12, 14, 17, 0, 0, 0, time.UTC)
+	event := notification.Event{ContainerName: "web", Action: "die", Labels: map[string]string{"image": "web:1"}}
+
+	for i := 0; i < cfg.EventThreshold; i++ {
+		throttler.Check(event, at)
+	}
+	notices, notify := throttler.Check(event, at)
+	if notify || len(notices) != 1 || notices[0].Action != "throttled" {
+		t.Fatalf("Check() over the threshold = %+v, %v, want the throttled notice", notices, notify)
+	}
+	if got, ok := notices[0].Labels["paused_for"]; ok {
+		t.Errorf("paused_for = %q, want none without a cooldown", got)
+	}
+
+	// Without a cooldown the next event ends the pause, well within the window
+	notices, notify = throttler.Check(event, at.Add(time.Second))
+	if !notify || len(notices) != 1 || notices[0].Action != "throttle_summary" {
+		t.Fatalf("Check() after the pause = %+v, %v, want the summary and the event", notices, notify)
+	}
+}
+
+func TestNotificationThrottler_SummaryWithoutCooldown(t *testing.T) {
+	throttler := NewNotificationThrottler(config.AppConfig{
+		WindowDuration: time.Hour,
+		EventThreshold: 1,
+	})
+	at := time.Date(2024, 12, 14, 17, 0, 0, 0, time.UTC)
+	event := notification.Event{ContainerName: "web", Action: "die", ExitStatus: "1"}
+	throttler.Check(event, at)
+	throttler.Check(event, at)
+
+	// The next detector check sends the summary, long before the window ends
+	summaries := throttler.Resume(at.Add(detectorCheckInterval))
+	if len(summaries) != 1 {
+		t.Fatalf("Resume() returned %d summaries, want 1", len(summaries))
+	}
+	if got := summaries[0].Labels["paused_for"]; got != "10s" {
+		t.Errorf("paused_for = %q, want the time until the check", got)
+	}
+	if got := summaries[0].Labels["suppressed"]; got != "1" {
+		t.Errorf("suppressed = %q, want 1", got)
+	}
+	if _, notify := throttler.Check(event, at.Add(time.Minute)); !notify {
+		t.Error("notification after the summary denied")
+	}
+}
+
+func TestNotificationThrottler_SummaryBeforeNextEvent(t *testing.T) {
+	throttler := NewNotificationThrottler(config.AppConfig{
+		WindowDuration:       time.Minute,
+		EventThreshold:       1,
+		NotificationCooldown: 10 * time.Minute,
+	})
+	at := time.Now()
+	event := notification.Event{ContainerName: "web", Action: "restart"}
+	throttler.Check(event, at)
+	throttler.Check(event, at)
+
+	// The pause ends with the next event, before Resume runs
+	notices, notify := throttler.Check(event, at.Add(10*time.Minute))
+	if !notify || len(notices) != 1 || notices[0].Action != "throttle_summary" {
+		t.Fatalf("Check() after the cooldown = %+v, %v, want the summary and the event", notices, notify)
+	}
+	if got := notices[0].Labels["paused_for"]; got != "10m0s" {
+		t.Errorf("paused_for = %q, want the cooldown", got)
+	}
+	if _, ok := notices[0].Labels["suppressed_exit_codes"]; ok {
+		t.Error("summary lists exit codes without any")
+	}
+}